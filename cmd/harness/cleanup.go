@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// runCleanup deletes the tables leaked by earlier runs: those named with
+// -table-prefix and older than -older-than.
+func runCleanup(name string, args []string) error {
+	var opts workload.CleanupOptions
+	flags, err := workload.ParseFlags(name, args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&opts.OlderThan, "older-than", 24*time.Hour, "spare tables created less than this long ago; 0 deletes every match")
+		fs.IntVar(&opts.Workers, "cleanup-workers", 10, "most tables deleted at once")
+		fs.BoolVar(&opts.DryRun, "dry-run", false, "list the tables that would be deleted without deleting them")
+	})
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err == nil && opts.Workers < 1 {
+		err = fmt.Errorf("-cleanup-workers must be at least 1, got %d", opts.Workers)
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "ParseFlags", Err: err}
+	}
+
+	ctx := context.Background()
+	stats := obsv.NewStats()
+	logger := &obsv.Logger{Verbosity: flags.Verbosity, Stats: stats}
+	loader, err := newSDKLoader(flags, logger, stats)
+	if err != nil {
+		return err
+	}
+	provider, err := sourceCredentials(ctx, flags, logger, loader)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.load(ctx, aws.NewCredentialsCache(provider))
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadConfig", Err: err}
+	}
+
+	h := &workload.Harness{
+		AWS:        cfg,
+		Flags:      flags,
+		Logger:     logger,
+		Stats:      stats,
+		Checkpoint: workload.NewCheckpoint(""),
+	}
+	if flags.Backend == workload.BackendMemory {
+		h.Memory = ddbtest.NewMemory()
+	}
+	result, err := workload.Cleanup(ctx, h, opts)
+	fmt.Fprintf(os.Stdout, "Cleanup: %d tables found, %d deleted, %d failed\n", result.Found, result.Deleted, result.Failed)
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Lou-Varndell/files/credlog"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// sourceCredentials returns the uncached provider the run signs with:
+// the static keypair, or the -credentials-secret keypair it bootstraps,
+// optionally used to assume -role-arn.
+func sourceCredentials(ctx context.Context, flags *workload.Config, logger *obsv.Logger, loader *sdkLoader) (aws.CredentialsProvider, error) {
+	var provider aws.CredentialsProvider = staticProvider
+	var err error
+	if flags.CredentialsSecret != "" {
+		// The static credentials only bootstrap access to the secret.
+		provider, err = newSecretCredentialsProvider(ctx, flags, logger, staticProvider, loader)
+		if err != nil {
+			return nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadConfig", Err: err}
+		}
+	}
+	if flags.RoleARN != "" {
+		provider, err = assumeRoleProvider(ctx, flags, provider, loader)
+		if err != nil {
+			return nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadConfig", Err: err}
+		}
+	}
+	return provider, nil
+}
+
+// assumeRoleProvider returns a provider that assumes -role-arn using the
+// base credentials, with the configured session tags.
+func assumeRoleProvider(ctx context.Context, flags *workload.Config, base aws.CredentialsProvider,
+	loader *sdkLoader) (aws.CredentialsProvider, error) {
+	stsCfg, err := loader.load(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	return credlog.AssumeRoleProvider(sts.NewFromConfig(stsCfg), credlog.Role{
+		ARN:               flags.RoleARN,
+		SessionName:       flags.RoleSessionName,
+		Duration:          flags.RoleDuration,
+		Tags:              flags.SessionTags,
+		TransitiveTagKeys: flags.TransitiveTagKeys,
+	}), nil
+}
+
+// newSecretCredentialsProvider returns a provider for -credentials-secret
+// that authenticates to Secrets Manager with bootstrap.
+func newSecretCredentialsProvider(ctx context.Context, flags *workload.Config, logger *obsv.Logger, bootstrap aws.CredentialsProvider,
+	loader *sdkLoader) (*credlog.SecretCredentialsProvider, error) {
+	smCfg, err := loader.load(ctx, bootstrap)
+	if err != nil {
+		return nil, err
+	}
+	return &credlog.SecretCredentialsProvider{
+		Client:   secretsmanager.NewFromConfig(smCfg),
+		SecretID: flags.CredentialsSecret,
+		Refresh:  flags.SecretRefresh,
+		Logger:   logger,
+	}, nil
+}
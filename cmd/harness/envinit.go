@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+)
+
+// composeTemplate is the docker-compose.yml written by env init.
+var composeTemplate = template.Must(template.New("compose").Parse(`# Generated by "{{.Command}}".
+# Start with "docker compose up -d"; the harness waits for readiness itself.
+services:
+  localstack:
+    image: {{.Image}}
+    ports:
+      - "127.0.0.1:{{.Port}}:4566"
+    environment:
+      - SERVICES={{.Services}}
+      - AWS_DEFAULT_REGION={{.Region}}
+{{- if .Persist}}
+      - PERSISTENCE=1
+{{- end}}
+    volumes:
+      - "./localstack:/var/lib/localstack"
+{{- if .Docker}}
+      # Lambda runs functions in sibling containers.
+      - "/var/run/docker.sock:/var/run/docker.sock"
+{{- end}}
+    healthcheck:
+      test: ["CMD", "curl", "-fs", "http://localhost:4566/_localstack/health"]
+      interval: 5s
+      timeout: 3s
+      retries: 20
+`))
+
+// composeConfig fills composeTemplate.
+type composeConfig struct {
+	Command  string
+	Image    string
+	Port     string
+	Services string
+	Region   string
+	Persist  bool
+	Docker   bool
+}
+
+// runEnv dispatches the env subcommands.
+func runEnv(name string, args []string) error {
+	if len(args) == 0 || args[0] != "init" {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "Env", Err: errors.New("usage: env init [-output file] [-force] [-persist] [harness flags]")}
+	}
+	return runEnvInit(filepath.Base(name)+" env init", args[1:])
+}
+
+// runEnvInit writes a docker-compose.yml whose LocalStack serves what the
+// given harness flags will use.
+func runEnvInit(name string, args []string) error {
+	var output string
+	var force, persist bool
+	flags, err := workload.ParseFlags(name, args, func(fs *flag.FlagSet) {
+		fs.StringVar(&output, "output", "docker-compose.yml", "`file` to write")
+		fs.BoolVar(&force, "force", false, "overwrite -output if it exists")
+		fs.BoolVar(&persist, "persist", false, "keep LocalStack state in ./localstack across restarts")
+	})
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "ParseFlags", Err: err}
+	}
+
+	endpoint, err := url.Parse(flags.Endpoint)
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "EnvInit", Err: fmt.Errorf("-endpoint: %w", err)}
+	}
+	port := endpoint.Port()
+	if port == "" {
+		port = "4566"
+	}
+
+	cfg := composeConfig{
+		Command:  strings.Join(append([]string{name}, args...), " "),
+		Image:    flags.LocalStackImage,
+		Port:     port,
+		Services: strings.Join(requiredServices(flags), ","),
+		Region:   flags.Region,
+		Persist:  persist,
+		Docker:   slices.Contains(flags.Modules, "lambda"),
+	}
+
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(output, mode, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "EnvInit", Err: fmt.Errorf("%s already exists; use -force to overwrite it", output)}
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "EnvInit", Err: err}
+	}
+	if err := composeTemplate.Execute(f, cfg); err != nil {
+		f.Close()
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "EnvInit", Err: err}
+	}
+	if err := f.Close(); err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "EnvInit", Err: err}
+	}
+	fmt.Printf("Wrote %s with services %s\n", output, cfg.Services)
+	return nil
+}
+
+// requiredServices lists, sorted, the LocalStack services the modules and
+// the optional integrations in flags call.
+func requiredServices(flags *workload.Config) []string {
+	var services []string
+	for _, m := range flags.Modules {
+		services = append(services, workload.ModuleServices[m]...)
+	}
+	if flags.RoleARN != "" {
+		services = append(services, "sts")
+	}
+	if flags.CredentialsSecret != "" {
+		services = append(services, "secretsmanager")
+	}
+	if flags.SSMPath != "" {
+		services = append(services, "ssm")
+	}
+	if flags.CloudWatchNamespace != "" {
+		services = append(services, "cloudwatch")
+	}
+	if flags.CloudWatchLogGroup != "" {
+		services = append(services, "logs")
+	}
+	if flags.EventBridgeBus != "" {
+		services = append(services, "events")
+	}
+	slices.Sort(services)
+	return slices.Compact(services)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Lou-Varndell/files/workload"
+)
+
+func TestRequiredServices(t *testing.T) {
+	flags, err := workload.ParseFlags("harness", []string{"-modules", "kms,sns", "-cloudwatch-log-group", "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dynamodb", "kms", "logs", "sns", "sqs"}
+	if got := requiredServices(flags); !slices.Equal(got, want) {
+		t.Errorf("got services %v, want %v", got, want)
+	}
+}
+
+func TestEnvInit(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "docker-compose.yml")
+	args := []string{"env", "init", "-output", output, "-endpoint", "http://localhost:4510", "-modules", "s3"}
+	if err := run("harness", args); err != nil {
+		t.Fatalf("env init: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"127.0.0.1:4510:4566"`, "SERVICES=s3\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("compose file lacks %q:\n%s", want, data)
+		}
+	}
+
+	if err := run("harness", args); err == nil {
+		t.Error("env init overwrote an existing file without -force")
+	}
+	if err := run("harness", append(args, "-force")); err != nil {
+		t.Errorf("env init -force: %v", err)
+	}
+}
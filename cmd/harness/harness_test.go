@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// newTestHarness builds a harness for endpoint from args, loading its
+// config the way run does.
+func newTestHarness(t *testing.T, endpoint string, args ...string) (*workload.Harness, *ddbtest.RecordingSink) {
+	t.Helper()
+	return workload.NewTestHarness(t, endpoint, func(flags *workload.Config, logger *obsv.Logger, stats *obsv.Stats) (aws.Config, *workload.EndpointFailover, error) {
+		loader, err := newSDKLoader(flags, logger, stats)
+		if err != nil {
+			return aws.Config{}, nil, err
+		}
+		cfg, err := loader.load(context.Background(), ddbtest.TestCredentials)
+		return cfg, loader.failover, err
+	}, args...)
+}
+
+// runHarness runs the harness against endpoint with args, failing the
+// test on any error.
+func runHarness(t *testing.T, endpoint string, args ...string) {
+	t.Helper()
+	if err := run("harness", append([]string{"-endpoint", endpoint}, args...)); err != nil {
+		t.Fatalf("run %v: %v", args, err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// newHTTPClient builds the HTTP client shared by every SDK client from
+// the transport flags.
+func newHTTPClient(flags *workload.Config) (*awshttp.BuildableClient, error) {
+	tlsConfig, err := newTLSConfig(flags)
+	if err != nil {
+		return nil, err
+	}
+	proxy := proxyConfig(flags).ProxyFunc()
+	return awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+		if tlsConfig != nil {
+			t.TLSClientConfig = tlsConfig
+		}
+		t.MaxIdleConnsPerHost = flags.MaxIdleConnsPerHost
+		t.MaxIdleConns = max(t.MaxIdleConns, flags.MaxIdleConnsPerHost)
+		t.IdleConnTimeout = flags.IdleConnTimeout
+		t.TLSHandshakeTimeout = flags.TLSHandshakeTimeout
+		t.ResponseHeaderTimeout = flags.ResponseHeaderTimeout
+	}).WithDialerOptions(func(d *net.Dialer) {
+		d.Timeout = flags.DialTimeout
+	}), nil
+}
+
+// traceConnections returns a wrapper that counts, in stats, whether each
+// request went out on a new connection or reused an idle one.
+func traceConnections(stats *obsv.Stats) func(aws.HTTPClient) aws.HTTPClient {
+	return func(next aws.HTTPClient) aws.HTTPClient {
+		return &connTracer{next: next, stats: stats}
+	}
+}
+
+type connTracer struct {
+	next  aws.HTTPClient
+	stats *obsv.Stats
+}
+
+func (c *connTracer) Do(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.stats.ConnectionUsed(info.Reused, info.IdleTime)
+		},
+	}
+	return c.next.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// newTLSConfig returns the TLS settings for -ca-bundle, -client-cert and
+// -insecure-skip-verify, or nil to keep the defaults.
+func newTLSConfig(flags *workload.Config) (*tls.Config, error) {
+	if flags.CABundle == "" && flags.ClientCert == "" && !flags.InsecureSkipVerify {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: flags.InsecureSkipVerify,
+	}
+
+	if flags.CABundle != "" {
+		pem, err := os.ReadFile(flags.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("read -ca-bundle: %w", err)
+		}
+		// The bundle adds to the system roots so real AWS endpoints still verify.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("-ca-bundle %s contains no PEM certificates", flags.CABundle)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if flags.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(flags.ClientCert, flags.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load -client-cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// proxyConfig starts from HTTP_PROXY, HTTPS_PROXY and NO_PROXY and lets
+// -proxy and -no-proxy override them. Requests to localhost are never
+// proxied.
+func proxyConfig(flags *workload.Config) *httpproxy.Config {
+	cfg := httpproxy.FromEnvironment()
+	if flags.Proxy != "" {
+		cfg.HTTPProxy = flags.Proxy
+		cfg.HTTPSProxy = flags.Proxy
+	}
+	if flags.NoProxy != "" {
+		cfg.NoProxy = flags.NoProxy
+	}
+	return cfg
+}
+
+// describeProxy reports the proxy, if any, the endpoint is reached through.
+func describeProxy(flags *workload.Config) string {
+	endpoint, err := url.Parse(flags.Endpoint)
+	if err != nil {
+		return "unknown"
+	}
+	proxy, err := proxyConfig(flags).ProxyFunc()(endpoint)
+	switch {
+	case err != nil:
+		return "invalid: " + err.Error()
+	case proxy == nil:
+		return "direct"
+	default:
+		return proxy.Redacted()
+	}
+}
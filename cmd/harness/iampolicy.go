@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// iamServices maps SDK service IDs to IAM action prefixes where they
+// differ from the lowercased ID.
+var iamServices = map[string]string{
+	"CloudWatch Logs": "logs",
+	"EventBridge":     "events",
+	"Secrets Manager": "secretsmanager",
+}
+
+// iamActions maps operations to the IAM actions that authorize them
+// where the two differ.
+var iamActions = map[string]string{
+	"s3:CompleteMultipartUpload":       "s3:PutObject",
+	"s3:CreateMultipartUpload":         "s3:PutObject",
+	"s3:UploadPart":                    "s3:PutObject",
+	"s3:HeadObject":                    "s3:GetObject",
+	"s3:DeleteObjects":                 "s3:DeleteObject",
+	"s3:HeadBucket":                    "s3:ListBucket",
+	"s3:ListObjectsV2":                 "s3:ListBucket",
+	"s3:ListMultipartUploads":          "s3:ListBucketMultipartUploads",
+	"sqs:SendMessageBatch":             "sqs:SendMessage",
+	"sqs:DeleteMessageBatch":           "sqs:DeleteMessage",
+	"sqs:ChangeMessageVisibilityBatch": "sqs:ChangeMessageVisibility",
+	"lambda:Invoke":                    "lambda:InvokeFunction",
+}
+
+// unauthorized lists the calls IAM never denies, which the policy
+// leaves out.
+var unauthorized = []string{"sts:GetCallerIdentity", "sts:AssumeRoleWithWebIdentity", "sts:AssumeRoleWithSAML"}
+
+// iamAccess is one IAM action on one resource.
+type iamAccess struct {
+	Action   string
+	Resource string
+}
+
+// policyRecorder records the IAM actions and resources of every call
+// the SDK makes, for -iam-policy.
+type policyRecorder struct {
+	mu       sync.Mutex
+	accesses map[iamAccess]bool
+}
+
+func newPolicyRecorder() *policyRecorder {
+	return &policyRecorder{accesses: make(map[iamAccess]bool)}
+}
+
+// recordPolicy has every config l loads record its calls in r.
+func (l *sdkLoader) recordPolicy(r *policyRecorder) {
+	if r != nil {
+		l.opts = append(l.opts, config.WithAPIOptions([]func(*middleware.Stack) error{r.middleware}))
+	}
+}
+
+// middleware records each call as it is made, before it can fail, since
+// a call the backend denies still needs its permission.
+func (r *policyRecorder) middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RecordIAMAccess",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+			middleware.InitializeOutput, middleware.Metadata, error) {
+			accesses := accessesOf(middleware.GetServiceID(ctx), middleware.GetOperationName(ctx), awsmiddleware.GetRegion(ctx), in.Parameters)
+			r.mu.Lock()
+			for _, a := range accesses {
+				r.accesses[a] = true
+			}
+			r.mu.Unlock()
+			return next.HandleInitialize(ctx, in)
+		}), middleware.Before)
+}
+
+// accessesOf returns what IAM must allow for op of service to be called
+// with params.
+func accessesOf(service, op, region string, params any) []iamAccess {
+	prefix, ok := iamServices[service]
+	if !ok {
+		prefix = strings.ToLower(strings.ReplaceAll(service, " ", ""))
+	}
+	action := prefix + ":" + op
+	if slices.Contains(unauthorized, action) {
+		return nil
+	}
+	if mapped, ok := iamActions[action]; ok {
+		action = mapped
+	}
+	arn := func(resource string) string {
+		return "arn:aws:" + prefix + ":" + region + ":*:" + resource
+	}
+
+	var resources []string
+	switch prefix {
+	case "dynamodb":
+		return dynamoAccesses(action, params, arn)
+	case "s3":
+		bucket, key := stringField(params, "Bucket"), stringField(params, "Key")
+		switch {
+		case bucket == "":
+		case key != "" && strings.HasPrefix(bucket, "arn:"):
+			resources = append(resources, bucket+"/object/"+key)
+		case key != "":
+			resources = append(resources, "arn:aws:s3:::"+bucket+"/"+key)
+		case strings.HasPrefix(bucket, "arn:"):
+			resources = append(resources, bucket)
+		default:
+			resources = append(resources, "arn:aws:s3:::"+bucket)
+		}
+	case "sqs":
+		name := stringField(params, "QueueName")
+		if url := stringField(params, "QueueUrl"); url != "" {
+			name = url[strings.LastIndex(url, "/")+1:]
+		}
+		if name != "" {
+			resources = append(resources, arn(name))
+		}
+	case "kinesis":
+		if stream := stringField(params, "StreamARN"); stream != "" {
+			resources = append(resources, stream)
+		} else if name := stringField(params, "StreamName"); name != "" {
+			resources = append(resources, arn("stream/"+name))
+		}
+	case "sns":
+		if topic := stringField(params, "TopicArn"); topic != "" {
+			resources = append(resources, topic)
+		} else if sub := stringField(params, "SubscriptionArn"); sub != "" {
+			resources = append(resources, sub[:max(strings.LastIndex(sub, ":"), 0)])
+		} else if name := stringField(params, "Name"); name != "" {
+			resources = append(resources, arn(name))
+		}
+	case "kms":
+		if id := stringField(params, "KeyId"); strings.HasPrefix(id, "arn:") {
+			resources = append(resources, id)
+		} else if strings.HasPrefix(id, "alias/") {
+			resources = append(resources, arn(id))
+		} else if id != "" {
+			resources = append(resources, arn("key/"+id))
+		}
+	case "lambda":
+		if fn := stringField(params, "FunctionName"); strings.HasPrefix(fn, "arn:") {
+			resources = append(resources, fn)
+		} else if fn != "" {
+			resources = append(resources, arn("function:"+fn))
+		}
+	case "logs":
+		if group := stringField(params, "LogGroupName"); group != "" {
+			resource := "log-group:" + group
+			if stream := stringField(params, "LogStreamName"); stream != "" {
+				resource += ":log-stream:" + stream
+			}
+			resources = append(resources, arn(resource))
+		}
+	case "secretsmanager":
+		// Secret ARNs end in a random suffix the ID does not carry.
+		if id := stringField(params, "SecretId"); strings.HasPrefix(id, "arn:") {
+			resources = append(resources, id)
+		} else if id != "" {
+			resources = append(resources, arn("secret:"+id+"-*"))
+		}
+	case "ssm":
+		name := stringField(params, "Name")
+		if name == "" {
+			name = stringField(params, "Path")
+		}
+		if name != "" {
+			resources = append(resources, arn("parameter/"+strings.TrimPrefix(name, "/")))
+		}
+	case "sts":
+		if role := stringField(params, "RoleArn"); role != "" {
+			resources = append(resources, role)
+		}
+	case "events":
+		entries := reflect.Indirect(reflect.ValueOf(params)).FieldByName("Entries")
+		for i := 0; entries.Kind() == reflect.Slice && i < entries.Len(); i++ {
+			bus := stringField(entries.Index(i).Addr().Interface(), "EventBusName")
+			switch {
+			case strings.HasPrefix(bus, "arn:"):
+			case bus == "":
+				bus = arn("event-bus/default")
+			default:
+				bus = arn("event-bus/" + bus)
+			}
+			if !slices.Contains(resources, bus) {
+				resources = append(resources, bus)
+			}
+		}
+	}
+	if len(resources) == 0 {
+		resources = []string{"*"}
+	}
+	accesses := make([]iamAccess, len(resources))
+	for i, resource := range resources {
+		accesses[i] = iamAccess{Action: action, Resource: resource}
+	}
+	return accesses
+}
+
+// dynamoAccesses returns the accesses of a DynamoDB call, which for the
+// batch and transaction calls span several tables. IAM authorizes a
+// transaction by the item actions it is made of.
+func dynamoAccesses(action string, params any, arn func(string) string) []iamAccess {
+	table := func(name *string) iamAccess {
+		return iamAccess{Action: action, Resource: arn("table/" + aws.ToString(name))}
+	}
+	var accesses []iamAccess
+	switch in := params.(type) {
+	case *dynamodb.BatchWriteItemInput:
+		for name := range in.RequestItems {
+			accesses = append(accesses, table(&name))
+		}
+	case *dynamodb.BatchGetItemInput:
+		for name := range in.RequestItems {
+			accesses = append(accesses, table(&name))
+		}
+	case *dynamodb.TransactWriteItemsInput:
+		for _, item := range in.TransactItems {
+			switch {
+			case item.Put != nil:
+				accesses = append(accesses, iamAccess{Action: "dynamodb:PutItem", Resource: arn("table/" + aws.ToString(item.Put.TableName))})
+			case item.Update != nil:
+				accesses = append(accesses, iamAccess{Action: "dynamodb:UpdateItem", Resource: arn("table/" + aws.ToString(item.Update.TableName))})
+			case item.Delete != nil:
+				accesses = append(accesses, iamAccess{Action: "dynamodb:DeleteItem", Resource: arn("table/" + aws.ToString(item.Delete.TableName))})
+			case item.ConditionCheck != nil:
+				accesses = append(accesses, iamAccess{Action: "dynamodb:ConditionCheckItem", Resource: arn("table/" + aws.ToString(item.ConditionCheck.TableName))})
+			}
+		}
+	default:
+		if name := stringField(params, "TableName"); name != "" {
+			accesses = append(accesses, table(&name))
+		} else {
+			accesses = append(accesses, iamAccess{Action: action, Resource: "*"})
+		}
+	}
+	return accesses
+}
+
+// stringField returns the string or *string field name of the struct
+// params points to, or "" if it has none.
+func stringField(params any, name string) string {
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	f := v.FieldByName(name)
+	if f.Kind() == reflect.Pointer && !f.IsNil() {
+		f = f.Elem()
+	}
+	if f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// generatedSuffix matches the suffixes the harness makes resource names
+// unique with: an HHMMSS time, or a -table-naming random or ULID name,
+// lower cased in bucket names.
+var generatedSuffix = regexp.MustCompile(`(\d{6}|[0-9a-f]{12}|(?i:[0-9A-HJKMNP-TV-Z]{26}))(/.*)?$`)
+
+// generalize replaces a generated suffix in the last name of resource
+// with a wildcard, so the policy also covers the names of later runs.
+func generalize(resource string) string {
+	if resource == "*" {
+		return resource
+	}
+	return generatedSuffix.ReplaceAllString(resource, "*$2")
+}
+
+// iamPolicy is an IAM policy document.
+type iamPolicy struct {
+	Version   string
+	Statement []iamStatement
+}
+
+// iamStatement is one statement of an IAM policy document.
+type iamStatement struct {
+	Effect   string
+	Action   []string
+	Resource []string
+}
+
+// policy returns the recorded accesses as an IAM policy document, one
+// statement per set of resources that share their actions.
+func (r *policyRecorder) policy() iamPolicy {
+	r.mu.Lock()
+	resources := make(map[string][]string)
+	for a := range r.accesses {
+		resource := generalize(a.Resource)
+		if !slices.Contains(resources[a.Action], resource) {
+			resources[a.Action] = append(resources[a.Action], resource)
+		}
+	}
+	r.mu.Unlock()
+
+	byResources := make(map[string]*iamStatement)
+	for action, rs := range resources {
+		slices.Sort(rs)
+		key := strings.Join(rs, "\n")
+		st, ok := byResources[key]
+		if !ok {
+			st = &iamStatement{Effect: "Allow", Resource: rs}
+			byResources[key] = st
+		}
+		st.Action = append(st.Action, action)
+	}
+	statements := make([]iamStatement, 0, len(byResources))
+	for _, st := range byResources {
+		slices.Sort(st.Action)
+		statements = append(statements, *st)
+	}
+	slices.SortFunc(statements, func(a, b iamStatement) int { return strings.Compare(a.Action[0], b.Action[0]) })
+	return iamPolicy{Version: "2012-10-17", Statement: statements}
+}
+
+// write saves the policy to path.
+func (r *policyRecorder) write(path string) error {
+	data, err := json.MarshalIndent(r.policy(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+func TestAccessesOf(t *testing.T) {
+	const region = "us-east-1"
+	for _, tc := range []struct {
+		name    string
+		service string
+		op      string
+		params  any
+		want    []iamAccess
+	}{
+		{"table", "DynamoDB", "GetItem", &dynamodb.GetItemInput{TableName: aws.String("T")},
+			[]iamAccess{{"dynamodb:GetItem", "arn:aws:dynamodb:us-east-1:*:table/T"}}},
+		{"transaction", "DynamoDB", "TransactWriteItems", &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{TableName: aws.String("T")}},
+			{Delete: &types.Delete{TableName: aws.String("U")}},
+		}}, []iamAccess{
+			{"dynamodb:PutItem", "arn:aws:dynamodb:us-east-1:*:table/T"},
+			{"dynamodb:DeleteItem", "arn:aws:dynamodb:us-east-1:*:table/U"},
+		}},
+		{"unscoped", "DynamoDB", "ListTables", &dynamodb.ListTablesInput{},
+			[]iamAccess{{"dynamodb:ListTables", "*"}}},
+		{"object", "S3", "UploadPart", &s3.UploadPartInput{Bucket: aws.String("b"), Key: aws.String("k")},
+			[]iamAccess{{"s3:PutObject", "arn:aws:s3:::b/k"}}},
+		{"bucket", "S3", "CreateBucket", &s3.CreateBucketInput{Bucket: aws.String("b")},
+			[]iamAccess{{"s3:CreateBucket", "arn:aws:s3:::b"}}},
+		{"queue", "SQS", "SendMessageBatch", &sqs.SendMessageBatchInput{QueueUrl: aws.String("http://localhost:4566/000000000000/q")},
+			[]iamAccess{{"sqs:SendMessage", "arn:aws:sqs:us-east-1:*:q"}}},
+		{"role", "STS", "AssumeRole", &sts.AssumeRoleInput{RoleArn: aws.String("arn:aws:iam::000000000000:role/r")},
+			[]iamAccess{{"sts:AssumeRole", "arn:aws:iam::000000000000:role/r"}}},
+		{"always allowed", "STS", "GetCallerIdentity", &sts.GetCallerIdentityInput{}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := accessesOf(tc.service, tc.op, region, tc.params); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGeneralize(t *testing.T) {
+	for in, want := range map[string]string{
+		"arn:aws:dynamodb:us-east-1:*:table/MyTable01HWT25H88ZZZZZZZZZZZZZZZZ": "arn:aws:dynamodb:us-east-1:*:table/MyTable*",
+		"arn:aws:dynamodb:us-east-1:*:table/MyTable0123456789ab":               "arn:aws:dynamodb:us-east-1:*:table/MyTable*",
+		"arn:aws:s3:::mybucket142233/object.bin":                               "arn:aws:s3:::mybucket*/object.bin",
+		"arn:aws:s3:::mybucket01hwt25h88zzzzzzzzzzzzzzzz/object.bin":           "arn:aws:s3:::mybucket*/object.bin",
+		"arn:aws:iam::000000000000:role/harness":                               "arn:aws:iam::000000000000:role/harness",
+		"*":                                                                    "*",
+	} {
+		if got := generalize(in); got != want {
+			t.Errorf("generalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPolicyRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	h, _ := newTestHarness(t, srv.URL, "-quiet")
+	rec := newPolicyRecorder()
+	cfg := h.AWS.Copy()
+	cfg.APIOptions = append(cfg.APIOptions, rec.middleware)
+	client := dynamodb.NewFromConfig(cfg)
+	ctx := context.Background()
+
+	// Tables of two runs, which the policy covers with one pattern.
+	for _, name := range []string{"MyTable142233", "MyTable150102"} {
+		if _, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.ListTables(ctx, &dynamodb.ListTablesInput{}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := rec.write(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got iamPolicy
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("policy %s: %v", data, err)
+	}
+	table := "arn:aws:dynamodb:" + h.Flags.Region + ":*:table/MyTable*"
+	want := iamPolicy{Version: "2012-10-17", Statement: []iamStatement{
+		{Effect: "Allow", Action: []string{"dynamodb:DeleteTable", "dynamodb:DescribeTable"}, Resource: []string{table}},
+		{Effect: "Allow", Action: []string{"dynamodb:ListTables"}, Resource: []string{"*"}},
+	}}
+	if len(got.Statement) != len(want.Statement) {
+		t.Fatalf("policy %s, want %+v", data, want)
+	}
+	for i, st := range got.Statement {
+		w := want.Statement[i]
+		if st.Effect != w.Effect || !slices.Equal(st.Action, w.Action) || !slices.Equal(st.Resource, w.Resource) {
+			t.Errorf("statement %d is %+v, want %+v", i, st, w)
+		}
+	}
+}
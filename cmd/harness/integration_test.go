@@ -0,0 +1,78 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lou-Varndell/files/credlog"
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestRefreshLoggingProviderAssumeRole(t *testing.T) {
+	endpoint := ddbtest.NewLocalStack(t)
+	h, sink := newTestHarness(t, endpoint, "-role-arn", "arn:aws:iam::000000000000:role/harness")
+	ctx := context.Background()
+
+	loader, err := newSDKLoader(h.Flags, h.Logger, h.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := assumeRoleProvider(ctx, h.Flags, ddbtest.TestCredentials, loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &credlog.RefreshLoggingProvider{
+		Provider: aws.NewCredentialsCache(source),
+		Logger:   h.Logger,
+		Stats:    h.Stats,
+	}
+
+	for i := 0; i < 3; i++ {
+		creds, err := provider.Retrieve(ctx)
+		if err != nil {
+			t.Fatalf("retrieve %d: %v", i, err)
+		}
+		if creds.SessionToken == "" || !creds.CanExpire {
+			t.Fatalf("retrieve %d: want expiring session credentials, got %+v", i, creds)
+		}
+	}
+	if n := sink.Count(obsv.CategoryCredentials, "REFRESHED"); n != 1 {
+		t.Errorf("logged %d refreshes for cached credentials, want 1", n)
+	}
+	if w := h.Stats.TakeWindow(); w.CredentialRefreshes != 1 || w.CredentialFailures != 0 {
+		t.Errorf("stats counted %d refreshes and %d failures, want 1 and 0", w.CredentialRefreshes, w.CredentialFailures)
+	}
+}
+
+func TestRefreshLoggingProviderFailure(t *testing.T) {
+	h, sink := newTestHarness(t, "http://localhost:1")
+	provider := &credlog.RefreshLoggingProvider{
+		Provider: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("no credentials")
+		}),
+		Logger: h.Logger,
+		Stats:  h.Stats,
+	}
+
+	_, err := provider.Retrieve(context.Background())
+	var he *obsv.HarnessError
+	if !errors.As(err, &he) || he.Class != obsv.ClassCredentials {
+		t.Fatalf("got %v, want a %s error", err, obsv.ClassCredentials)
+	}
+	if sink.Count(obsv.CategoryCredentials, "failed to retrieve") != 1 {
+		t.Error("failure was not logged")
+	}
+	if w := h.Stats.TakeWindow(); w.CredentialFailures != 1 {
+		t.Errorf("stats counted %d failures, want 1", w.CredentialFailures)
+	}
+}
+
+func TestRun(t *testing.T) {
+	endpoint := ddbtest.NewLocalStack(t)
+	runHarness(t, endpoint, "-iterations", "1", "-interval", "0", "-items", "2", "-delete-tables")
+}
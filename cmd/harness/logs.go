@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+// runLogs dispatches the logs subcommands.
+func runLogs(name string, args []string) error {
+	if len(args) == 0 || args[0] != "cat" {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "Logs", Err: errors.New("usage: logs cat [-category name] [-grep text] [-since time] [-text] file...")}
+	}
+	return runLogsCat(filepath.Base(name)+" logs cat", args[1:], os.Stdout)
+}
+
+// eventFilter selects the events logs cat prints.
+type eventFilter struct {
+	category string
+	grep     string
+	since    time.Time
+}
+
+func (f eventFilter) match(e obsv.Event) bool {
+	return (f.category == "" || e.Category == f.category) &&
+		strings.Contains(e.Message, f.grep) &&
+		!e.Time.Before(f.since)
+}
+
+// runLogsCat prints the events of -events-file outputs, compressed or
+// not, that pass the filter flags.
+func runLogsCat(name string, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var filter eventFilter
+	var since string
+	var text bool
+	fs.StringVar(&filter.category, "category", "", "print only events of this `category`: credentials, operation or sdk")
+	fs.StringVar(&filter.grep, "grep", "", "print only events whose message contains this `text`")
+	fs.StringVar(&since, "since", "", "print only events at or after this RFC 3339 `time`")
+	fs.BoolVar(&text, "text", false, "print events as console lines rather than JSON")
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err == nil && since != "" {
+		filter.since, err = time.Parse(time.RFC3339, since)
+	}
+	if err == nil && fs.NArg() == 0 {
+		err = errors.New("no event files given")
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "ParseFlags", Err: err}
+	}
+
+	enc := json.NewEncoder(w)
+	print := func(e obsv.Event) error {
+		if !filter.match(e) {
+			return nil
+		}
+		if text {
+			_, err := fmt.Fprintf(w, "%s [%s] %s\n", e.Time.Format(time.RFC3339Nano), strings.ToUpper(e.Category), e.Message)
+			return err
+		}
+		return enc.Encode(e)
+	}
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LogsCat", Err: err}
+		}
+		err = obsv.ReadEvents(f, print)
+		f.Close()
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "LogsCat", Err: fmt.Errorf("%s: %w", path, err)}
+		}
+	}
+	return nil
+}
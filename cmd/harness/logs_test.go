@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+func TestLogsCatFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl.gz")
+	sink, err := obsv.NewFileSink(path, obsv.CompressGzip, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sink.Write(obsv.Event{Time: start, Category: obsv.CategoryCredentials, Message: "Credentials refreshed"})
+	sink.Write(obsv.Event{Time: start.Add(time.Minute), Category: obsv.CategoryOperation, Message: "Table created: MyTable1"})
+	sink.Write(obsv.Event{Time: start.Add(2 * time.Minute), Category: obsv.CategoryOperation, Message: "Table deleted: MyTable1"})
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	err = runLogsCat("harness logs cat", []string{"-category", "operation", "-grep", "deleted", "-text", path}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2024-05-01T12:02:00Z [OPERATION] Table deleted: MyTable1\n"; out.String() != want {
+		t.Errorf("output %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	err = runLogsCat("harness logs cat", []string{"-since", "2024-05-01T12:01:00Z", path}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 2 || !strings.HasPrefix(out.String(), `{"time":"2024-05-01T12:01:00Z"`) {
+		t.Errorf("output %q, want the two events from 12:01 as JSON", out.String())
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Lou-Varndell/files/credlog"
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/smithy-go/middleware"
+)
+
+func main() {
+	log.SetOutput(obsv.NewRedactingWriter(os.Stderr))
+	err := run(os.Args[0], os.Args[1:])
+	if err != nil {
+		log.Printf("run failed: %v", err)
+	}
+	os.Exit(obsv.ReportError(os.Stderr, err))
+}
+
+// staticProvider holds the LocalStack test keypair the run signs with,
+// or bootstraps its other credentials with.
+var staticProvider = credentials.StaticCredentialsProvider{
+	Value: aws.Credentials{
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+		SessionToken:    "",
+	},
+}
+
+// sdkLoader loads SDK configs that share the run's endpoint, HTTP client
+// and logging. Wrappers such as a cassette go over each loaded config's
+// HTTP client rather than into the options, because the SDK applies
+// settings like AWS_CA_BUNDLE only to a client it can still build.
+type sdkLoader struct {
+	opts     []func(*config.LoadOptions) error
+	wrap     []func(aws.HTTPClient) aws.HTTPClient
+	failover *workload.EndpointFailover
+}
+
+func newSDKLoader(flags *workload.Config, logger *obsv.Logger, stats *obsv.Stats, wrap ...func(aws.HTTPClient) aws.HTTPClient) (*sdkLoader, error) {
+	httpClient, err := newHTTPClient(flags)
+	if err != nil {
+		return nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "NewHTTPClient", Err: err}
+	}
+	failover, err := workload.NewEndpointFailover(flags, logger)
+	if err != nil {
+		return nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "NewEndpointFailover", Err: err}
+	}
+	apiOptions := []func(*middleware.Stack) error{logSigner(logger), diagnoseSkew(logger), recordAttempts(stats)}
+	if len(flags.Endpoints) > 1 {
+		apiOptions = append(apiOptions, failover.Middleware)
+	}
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(flags.Region),
+		config.WithBaseEndpoint(flags.Endpoint),
+		config.WithHTTPClient(httpClient),
+		config.WithRetryer(newRetryer(flags)),
+		config.WithLogger(logger),
+		config.WithClientLogMode(logger.ClientLogMode()),
+		config.WithAPIOptions(apiOptions),
+	}
+	if flags.SigningVersion != "auto" {
+		opts = append(opts, config.WithAuthSchemePreference(flags.SigningVersion))
+	}
+	return &sdkLoader{opts: opts, wrap: wrap, failover: failover}, nil
+}
+
+// load returns a config that signs with creds.
+func (l *sdkLoader) load(ctx context.Context, creds aws.CredentialsProvider) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, append(slices.Clip(l.opts), config.WithCredentialsProvider(creds))...)
+	if err != nil {
+		return cfg, err
+	}
+	for _, w := range l.wrap {
+		cfg.HTTPClient = w(cfg.HTTPClient)
+	}
+	return cfg, nil
+}
+
+// run executes the harness, or the subcommand named by the first
+// argument, and returns a classified error on failure.
+func run(name string, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "env":
+			return runEnv(name, args[1:])
+		case "smoke":
+			return runSmoke(filepath.Base(name)+" smoke", args[1:])
+		case "logs":
+			return runLogs(name, args[1:])
+		case "cleanup":
+			return runCleanup(filepath.Base(name)+" cleanup", args[1:])
+		}
+	}
+
+	flags, err := workload.ParseFlags(name, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "ParseFlags", Err: err}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stats := obsv.NewStats()
+	defer stats.Report(os.Stdout)
+
+	logger := &obsv.Logger{Verbosity: flags.Verbosity, Stats: stats}
+	if flags.EventsFile != "" {
+		sink, err := obsv.NewFileSink(flags.EventsFile, flags.EventsCompress, flags.EventsFlush)
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "NewFileSink", Err: err}
+		}
+		logger.AddSink(sink)
+		defer func() {
+			if err := sink.Close(); err != nil {
+				log.Printf("Closing event file: %v", err)
+			}
+		}()
+	}
+
+	var containerEndpoint string
+	if flags.LocalStackContainer {
+		ctr, endpoint, err := ddbtest.StartLocalStack(ctx, flags.LocalStackImage)
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassUnavailable, Op: "StartLocalStack", Err: err}
+		}
+		defer ddbtest.StopLocalStack(ctr, logger)
+		logger.Operationf("LocalStack %s ready at %s", flags.LocalStackImage, endpoint)
+		containerEndpoint = endpoint
+		flags.Endpoint, flags.Endpoints = endpoint, []string{endpoint}
+	}
+
+	vcr, err := openCassette(flags)
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "OpenCassette", Err: err}
+	}
+	defer vcr.Close(logger)
+	if vcr != nil && vcr.replay != nil {
+		logger.Operationf("Replaying responses from %s; no requests reach %s", flags.ReplayPath, flags.Endpoint)
+	}
+
+	var policy *policyRecorder
+	if flags.IAMPolicyPath != "" {
+		policy = newPolicyRecorder()
+		defer func() {
+			if err := policy.write(flags.IAMPolicyPath); err != nil {
+				log.Printf("Writing IAM policy: %v", err)
+			} else {
+				logger.Operationf("Wrote an IAM policy for the run's calls to %s", flags.IAMPolicyPath)
+			}
+		}()
+	}
+
+	loader, err := newSDKLoader(flags, logger, stats, traceConnections(stats), vcr.wrap)
+	if err != nil {
+		return err
+	}
+	loader.recordPolicy(policy)
+
+	var configSource *workload.SSMConfigSource
+	if flags.SSMPath != "" {
+		ssmCfg, err := loader.load(ctx, staticProvider)
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadConfig", Err: err}
+		}
+		configSource, flags, err = workload.LoadSSMConfig(ctx, ssmCfg, name, args, flags, stats)
+		if err != nil {
+			return err
+		}
+		logger.Verbosity = flags.Verbosity
+		if containerEndpoint != "" {
+			flags.Endpoint, flags.Endpoints = containerEndpoint, []string{containerEndpoint}
+		}
+		loader, err = newSDKLoader(flags, logger, stats, traceConnections(stats), vcr.wrap)
+		if err != nil {
+			return err
+		}
+		loader.recordPolicy(policy)
+	}
+
+	sourceProvider, err := sourceCredentials(ctx, flags, logger, loader)
+	if err != nil {
+		return err
+	}
+
+	// Cache credentials for refresh support
+	cachedProvider := aws.NewCredentialsCache(sourceProvider)
+
+	// Wrap with logging provider
+	loggingProvider := &credlog.RefreshLoggingProvider{
+		Provider: cachedProvider,
+		Logger:   logger,
+		Stats:    stats,
+	}
+	if len(flags.SessionTags) > 0 {
+		tags := credlog.DescribeSessionTags(flags.SessionTags, flags.TransitiveTagKeys)
+		loggingProvider.OnRefresh = func(aws.Credentials) {
+			logger.Credentialf("Session tags applied: %s", tags)
+		}
+	}
+
+	cfg, err := loader.load(ctx, aws.NewCredentialsCache(loggingProvider))
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadConfig", Err: err}
+	}
+
+	// Start periodic TTL logger
+	loggingProvider.StartTTLLogger(ctx, 30*time.Second)
+
+	logger.Operationf("%s", time.Now().Format("150405"))
+	logger.Operationf("Endpoint %s via proxy: %s", loader.failover.Describe(), describeProxy(flags))
+	if flags.InsecureSkipVerify {
+		logger.Operationf("WARNING: TLS certificate verification is disabled")
+	}
+
+	checkpoint := workload.NewCheckpoint(flags.CheckpointPath)
+	if flags.Resume {
+		checkpoint, err = workload.LoadCheckpoint(flags.CheckpointPath)
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadCheckpoint", Err: err}
+		}
+		logger.Operationf("Resuming from checkpoint %s after %d iterations", flags.CheckpointPath, checkpoint.Iteration)
+	}
+
+	h := &workload.Harness{
+		AWS:          cfg,
+		Flags:        flags,
+		Logger:       logger,
+		Stats:        stats,
+		Checkpoint:   checkpoint,
+		ConfigSource: configSource,
+		Endpoints:    loader.failover,
+	}
+	if flags.Backend == workload.BackendMemory {
+		h.Memory = ddbtest.NewMemory()
+		logger.Operationf("Using the in-memory DynamoDB backend")
+	}
+	if flags.SSMPoll > 0 {
+		configSource.Watch(ctx, flags.SSMPoll, logger)
+	}
+	if err := workload.WaitReady(ctx, h); err != nil {
+		return err
+	}
+	if h.Memory == nil {
+		h.Features, err = workload.DetectFeatures(ctx, h)
+		if err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassUnavailable, Op: "DetectFeatures", Err: err}
+		}
+		logger.Operationf("Backend: %s", h.Features.Describe())
+	}
+	if flags.EventBridgeBus != "" {
+		h.Lifecycle = workload.NewLifecyclePublisher(h)
+	}
+	workloads, err := workload.NewWorkloads(h)
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "NewWorkloads", Err: err}
+	}
+	workloads = h.Features.SkipUnavailable(workloads, logger)
+	if len(workloads) == 0 {
+		return &obsv.HarnessError{Class: obsv.ClassUnavailable, Op: "NewWorkloads", Err: errors.New("the backend serves none of the selected modules")}
+	}
+
+	if flags.CloudWatchLogGroup != "" {
+		sink, err := obsv.NewCloudWatchLogsSink(ctx, cfg, flags.CloudWatchLogGroup, flags.CloudWatchLogStream,
+			flags.CloudWatchLogFlush, stats, flags.Timeouts())
+		if err != nil {
+			return err
+		}
+		logger.AddSink(sink)
+		defer sink.Close()
+	}
+
+	if flags.CloudWatchNamespace != "" {
+		publisher := obsv.NewMetricsPublisher(cfg, flags.CloudWatchNamespace, stats, logger, flags.Timeouts())
+		publisher.Start(ctx, flags.CloudWatchInterval)
+		defer publisher.Stop()
+	}
+
+	if flags.RestorePath != "" {
+		if err := workload.RestoreSnapshot(ctx, h, flags.RestorePath); err != nil {
+			return err
+		}
+	}
+
+	h.Drain = obsv.NewDrain()
+	drainOnSignal(ctx, h.Drain, flags.DrainTimeout, logger)
+	if err := workload.RunLoop(ctx, h, workloads); err != nil {
+		return err
+	}
+	if flags.SnapshotPath != "" {
+		return workload.TakeSnapshot(ctx, h, flags.SnapshotPath)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+)
+
+func TestMemoryRun(t *testing.T) {
+	err := run("harness", []string{"-endpoint", ddbtest.Offline, "-backend", "memory",
+		"-iterations", "2", "-interval", "0", "-items", "3", "-delete-tables", "-quiet"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// newRetryer returns the retryer constructor for the -retry flags. Each
+// client gets its own, and so its own retry quota.
+func newRetryer(flags *workload.Config) func() aws.Retryer {
+	standard := func(o *retry.StandardOptions) {
+		o.MaxAttempts = flags.RetryMaxAttempts
+		o.MaxBackoff = flags.RetryMaxBackoff
+		if flags.RetryTokens == 0 {
+			o.RateLimiter = ratelimit.None
+		} else {
+			o.RateLimiter = ratelimit.NewTokenRateLimit(flags.RetryTokens)
+		}
+	}
+	return func() aws.Retryer {
+		if flags.RetryMode == "adaptive" {
+			return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+				o.FailOnNoAttemptTokens = flags.RetryFailOnThrottle
+				o.StandardOptions = append(o.StandardOptions, standard)
+			})
+		}
+		return retry.NewStandard(standard)
+	}
+}
+
+// recordAttempts adds a middleware that counts, in stats, the attempts
+// the retryer made for each call.
+func recordAttempts(stats *obsv.Stats) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RecordAttempts",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (
+				middleware.InitializeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				if results, ok := retry.GetAttemptResults(metadata); ok {
+					stats.RecordAttempts(middleware.GetOperationName(ctx), len(results.Results))
+				}
+				return out, metadata, err
+			}), middleware.After)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// throttlingBackend throttles the first failures calls and then answers
+// ListTables.
+func throttlingBackend(failures int32) http.HandlerFunc {
+	var calls atomic.Int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ThrottlingException","message":"Rate exceeded"}`))
+			return
+		}
+		w.Write([]byte(`{"TableNames":[]}`))
+	}
+}
+
+func TestRetryAttemptsRecorded(t *testing.T) {
+	for _, mode := range []string{"standard", "adaptive"} {
+		t.Run(mode, func(t *testing.T) {
+			srv := httptest.NewServer(throttlingBackend(2))
+			defer srv.Close()
+			h, _ := newTestHarness(t, srv.URL, "-quiet",
+				"-retry-mode", mode, "-retry-max-attempts", "3", "-retry-max-backoff", "1ms")
+
+			client := dynamodb.NewFromConfig(h.AWS)
+			if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+				t.Fatalf("ListTables after two throttles: %v", err)
+			}
+			if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+				t.Fatal(err)
+			}
+
+			var report strings.Builder
+			h.Stats.Report(&report)
+			if !regexp.MustCompile(`(?m)^ListTables +2 +4 +1 +3$`).MatchString(report.String()) {
+				t.Errorf("summary does not show 2 calls, 4 attempts, 1 retried and at most 3:\n%s", report.String())
+			}
+		})
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(throttlingBackend(2))
+	defer srv.Close()
+	h, _ := newTestHarness(t, srv.URL, "-quiet", "-retry-max-attempts", "2", "-retry-max-backoff", "1ms")
+
+	client := dynamodb.NewFromConfig(h.AWS)
+	if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err == nil {
+		t.Fatal("ListTables succeeded with fewer attempts than throttles")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+// drainOnSignal starts drain on the first SIGINT or SIGTERM and aborts
+// the calls still in flight on the second. After that, signals are no
+// longer caught, so a third one kills the process.
+func drainOnSignal(ctx context.Context, drain *obsv.Drain, timeout time.Duration, logger *obsv.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case sig := <-signals:
+			n := drain.Start(timeout)
+			logger.Operationf("Received %s: shutting down once %d calls in flight finish, within -drain-timeout %s", sig, n, timeout)
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case sig := <-signals:
+			logger.Operationf("Received %s again: cancelling the calls still in flight", sig)
+			drain.Abort()
+		case <-ctx.Done():
+		}
+	}()
+}
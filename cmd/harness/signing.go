@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// signerNames maps the Authorization algorithm to the signer that wrote it.
+var signerNames = map[string]string{
+	"AWS4-HMAC-SHA256":       "sigv4",
+	"AWS4-ECDSA-P256-SHA256": "sigv4a",
+}
+
+// unloggedService is the service whose signing logSigner leaves out:
+// the CloudWatch Logs sink ships log events, and logging its own calls
+// would ship one more for every batch it sends.
+const unloggedService = "CloudWatch Logs"
+
+// logSigner adds a middleware that logs, after signing, which signer each
+// request was signed with.
+func logSigner(logger *obsv.Logger) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("LogSigner",
+			func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (
+				middleware.FinalizeOutput, middleware.Metadata, error) {
+				if req, ok := in.Request.(*smithyhttp.Request); ok && middleware.GetServiceID(ctx) != unloggedService {
+					logger.Logf(logging.Debug, "%s.%s signed with %s",
+						middleware.GetServiceID(ctx), middleware.GetOperationName(ctx), signerOf(req.Header.Get("Authorization")))
+				}
+				return next.HandleFinalize(ctx, in)
+			}), "Signing", middleware.After)
+	}
+}
+
+// skewCodes are the error codes of requests rejected for their
+// signature or its timestamp, which local clock drift can cause.
+var skewCodes = map[string]bool{
+	"RequestTimeTooSkewed":      true,
+	"RequestExpired":            true,
+	"InvalidSignatureException": true,
+	"SignatureDoesNotMatch":     true,
+}
+
+// diagnoseSkew adds a middleware that, when a request is rejected for
+// its signature, measures the skew between the local clock and the Date
+// the server answered with. The error then reports the skew and how to
+// fix it, and the first such diagnosis is logged.
+func diagnoseSkew(logger *obsv.Logger) func(*middleware.Stack) error {
+	var logged atomic.Bool
+	return func(stack *middleware.Stack) error {
+		return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("DiagnoseClockSkew",
+			func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (
+				middleware.DeserializeOutput, middleware.Metadata, error) {
+				sent := time.Now()
+				out, metadata, err := next.HandleDeserialize(ctx, in)
+				var apiErr smithy.APIError
+				if err == nil || !errors.As(err, &apiErr) || !skewCodes[apiErr.ErrorCode()] {
+					return out, metadata, err
+				}
+				resp, ok := out.RawResponse.(*smithyhttp.Response)
+				if !ok {
+					return out, metadata, err
+				}
+				server, perr := http.ParseTime(resp.Header.Get("Date"))
+				if perr != nil {
+					return out, metadata, err
+				}
+				// The server stamped its Date somewhere between sending and
+				// receiving; the midpoint is the best local estimate.
+				local := sent.Add(time.Since(sent) / 2)
+				err = &obsv.ClockSkewError{Skew: server.Sub(local), Err: err}
+				if logged.CompareAndSwap(false, true) {
+					logger.Credentialf("%s.%s rejected: %v", middleware.GetServiceID(ctx), middleware.GetOperationName(ctx), err)
+				}
+				return out, metadata, err
+			}), middleware.Before)
+	}
+}
+
+// signerOf names the signer from an Authorization header value.
+func signerOf(authorization string) string {
+	if authorization == "" {
+		return "none"
+	}
+	algorithm, _, _ := strings.Cut(authorization, " ")
+	if name, ok := signerNames[algorithm]; ok {
+		return name
+	}
+	return algorithm
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// skewedBackend rejects every signature and dates its answers offset
+// from the local clock.
+func skewedBackend(offset time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazon.coral.service#InvalidSignatureException","message":"Signature expired"}`))
+	}
+}
+
+func TestDiagnoseClockSkew(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		offset time.Duration
+		want   string
+	}{
+		{"behind", 17 * time.Minute, "behind the server's"},
+		{"ahead", -2 * time.Hour, "ahead of the server's"},
+		{"in sync", 0, "so this is not clock skew"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(skewedBackend(tc.offset))
+			defer srv.Close()
+			h, sink := newTestHarness(t, srv.URL, "-quiet", "-retry-max-attempts", "1")
+
+			_, err := dynamodb.NewFromConfig(h.AWS).ListTables(context.Background(), &dynamodb.ListTablesInput{})
+			var skew *obsv.ClockSkewError
+			if !errors.As(err, &skew) {
+				t.Fatalf("ListTables: %v, want a clock skew diagnosis", err)
+			}
+			if got := (skew.Skew - tc.offset).Abs(); got > 2*time.Second {
+				t.Errorf("measured skew %s, want about %s", skew.Skew, tc.offset)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error %q does not contain %q", err, tc.want)
+			}
+			if obsv.ClassOf(err) != obsv.ClassCredentials {
+				t.Errorf("class %s, want %s", obsv.ClassOf(err), obsv.ClassCredentials)
+			}
+			if sink.Count(obsv.CategoryCredentials, "rejected") != 1 {
+				t.Errorf("diagnosis logged %d times, want once", sink.Count(obsv.CategoryCredentials, "rejected"))
+			}
+		})
+	}
+}
+
+func TestLogSignerSkipsCloudWatchLogs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	h, sink := newTestHarness(t, srv.URL, "-v")
+
+	if _, err := dynamodb.NewFromConfig(h.AWS).ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+		t.Fatalf("ListTables: %v", err)
+	}
+	if _, err := cloudwatchlogs.NewFromConfig(h.AWS).DescribeLogGroups(context.Background(), &cloudwatchlogs.DescribeLogGroupsInput{}); err != nil {
+		t.Fatalf("DescribeLogGroups: %v", err)
+	}
+	if n := sink.Count(obsv.CategorySDK, "DynamoDB.ListTables signed with"); n != 1 {
+		t.Errorf("DynamoDB signing logged %d times, want once", n)
+	}
+	if n := sink.Count(obsv.CategorySDK, "signed with") - 1; n != 0 {
+		t.Errorf("CloudWatch Logs signing logged %d times, want none", n)
+	}
+}
+
+func TestSignerOf(t *testing.T) {
+	for _, tc := range []struct {
+		authorization string
+		want          string
+	}{
+		{"", "none"},
+		{"AWS4-HMAC-SHA256 Credential=AKIAEXAMPLE/20240501/us-east-1/dynamodb/aws4_request, SignedHeaders=host, Signature=abc", "sigv4"},
+		{"AWS4-ECDSA-P256-SHA256 Credential=AKIAEXAMPLE/20240501/s3/aws4_request, SignedHeaders=host, Signature=abc", "sigv4a"},
+		{"Bearer token", "Bearer"},
+	} {
+		if got := signerOf(tc.authorization); got != tc.want {
+			t.Errorf("signerOf(%.20q) = %q, want %q", tc.authorization, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// smokeCheck is one minimal, read-only call that proves a service answers.
+type smokeCheck struct {
+	Service string
+	Op      string
+	Call    func(ctx context.Context, cfg aws.Config) error
+}
+
+// smokeChecks are run by the smoke command, in order.
+var smokeChecks = []smokeCheck{
+	{"dynamodb", "ListTables", func(ctx context.Context, cfg aws.Config) error {
+		_, err := dynamodb.NewFromConfig(cfg).ListTables(ctx, &dynamodb.ListTablesInput{Limit: aws.Int32(1)})
+		return err
+	}},
+	{"s3", "ListBuckets", func(ctx context.Context, cfg aws.Config) error {
+		_, err := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.UsePathStyle = true
+		}).ListBuckets(ctx, &s3.ListBucketsInput{})
+		return err
+	}},
+	{"sqs", "ListQueues", func(ctx context.Context, cfg aws.Config) error {
+		_, err := sqs.NewFromConfig(cfg).ListQueues(ctx, &sqs.ListQueuesInput{MaxResults: aws.Int32(1)})
+		return err
+	}},
+	{"sts", "GetCallerIdentity", func(ctx context.Context, cfg aws.Config) error {
+		_, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		return err
+	}},
+}
+
+// runSmoke validates the environment the harness flags describe by
+// running every smoke check once and printing a pass/fail matrix.
+func runSmoke(name string, args []string) error {
+	flags, err := workload.ParseFlags(name, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "ParseFlags", Err: err}
+	}
+
+	ctx := context.Background()
+	stats := obsv.NewStats()
+	logger := &obsv.Logger{Verbosity: flags.Verbosity, Stats: stats}
+	loader, err := newSDKLoader(flags, logger, stats)
+	if err != nil {
+		return err
+	}
+	provider, err := sourceCredentials(ctx, flags, logger, loader)
+	if err != nil {
+		return err
+	}
+	cfg, err := loader.load(ctx, aws.NewCredentialsCache(provider))
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadConfig", Err: err}
+	}
+	return smoke(ctx, cfg, flags, stats, os.Stdout)
+}
+
+// smoke runs the checks against cfg and writes one row per check to w.
+// It fails with the class of the first failed check.
+func smoke(ctx context.Context, cfg aws.Config, flags *workload.Config, stats *obsv.Stats, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tOPERATION\tRESULT\tLATENCY\tDETAIL")
+
+	var first *obsv.HarnessError
+	failed := 0
+	for _, c := range smokeChecks {
+		start := time.Now()
+		err := obsv.Timed(ctx, stats, c.Op, flags.ControlTimeout, func(ctx context.Context) error {
+			return c.Call(ctx, cfg)
+		})
+		latency := time.Since(start).Round(time.Millisecond)
+		if err == nil {
+			fmt.Fprintf(tw, "%s\t%s\tPASS\t%s\t\n", c.Service, c.Op, latency)
+			continue
+		}
+		var he *obsv.HarnessError
+		errors.As(err, &he)
+		fmt.Fprintf(tw, "%s\t%s\tFAIL\t%s\t%s: %v\n", c.Service, c.Op, latency, he.Class, he.Err)
+		if first == nil {
+			first = he
+		}
+		failed++
+	}
+	tw.Flush()
+
+	if first != nil {
+		return &obsv.HarnessError{Class: first.Class, Op: "Smoke",
+			Err: fmt.Errorf("%d of %d checks failed, first %s: %w", failed, len(smokeChecks), first.Op, first.Err)}
+	}
+	return nil
+}
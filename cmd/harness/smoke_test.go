@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+// smokeBackend answers the DynamoDB, S3 and SQS smoke checks and rejects
+// the STS one as a bad token.
+func smokeBackend(w http.ResponseWriter, r *http.Request) {
+	switch target := r.Header.Get("X-Amz-Target"); {
+	case target == "DynamoDB_20120810.ListTables":
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Write([]byte(`{"TableNames":[]}`))
+	case target == "AmazonSQS.ListQueues":
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/":
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<ListAllMyBucketsResult><Buckets></Buckets></ListAllMyBucketsResult>`))
+	default:
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>InvalidClientTokenId</Code>` +
+			`<Message>The security token included in the request is invalid.</Message></Error></ErrorResponse>`))
+	}
+}
+
+func TestSmokeMatrix(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(smokeBackend))
+	defer srv.Close()
+	h, _ := newTestHarness(t, srv.URL, "-quiet")
+
+	var out bytes.Buffer
+	err := smoke(context.Background(), h.AWS, h.Flags, h.Stats, &out)
+	var he *obsv.HarnessError
+	if !errors.As(err, &he) || he.Class != obsv.ClassCredentials {
+		t.Fatalf("smoke error = %v, want a credentials failure", err)
+	}
+
+	for _, row := range []string{
+		`dynamodb +ListTables +PASS`,
+		`s3 +ListBuckets +PASS`,
+		`sqs +ListQueues +PASS`,
+		`sts +GetCallerIdentity +FAIL .*credentials: .*InvalidClientTokenId`,
+	} {
+		if !regexp.MustCompile(`(?m)^` + row).Match(out.Bytes()) {
+			t.Errorf("matrix has no row matching %q:\n%s", row, out.String())
+		}
+	}
+}
+
+func TestSmokeCommandRejectsBadFlags(t *testing.T) {
+	err := run("harness", []string{"smoke", "-endpoint", ""})
+	var he *obsv.HarnessError
+	if !errors.As(err, &he) || he.Class != obsv.ClassConfig {
+		t.Errorf("smoke with no endpoint = %v, want a config error", err)
+	}
+}
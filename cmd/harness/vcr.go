@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// maxRecordedRequestBody caps how much of each request body a cassette
+// keeps; request bodies are there for reading, not for replay.
+const maxRecordedRequestBody = 64 << 10
+
+// redactedHeaders are replaced outright, in both headers and presigned
+// query strings, before an exchange is written to a cassette; every other
+// value, and both bodies, go through obsv.Redact.
+var redactedHeaders = []string{"Authorization", "X-Amz-Security-Token"}
+
+// interaction is one request/response pair, stored one per line.
+type interaction struct {
+	Key      string           `json:"key"`
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response,omitempty"`
+	// Error is set instead of Response when the request failed to send.
+	Error string `json:"error,omitempty"`
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cassette records every HTTP exchange to a file with -record, or serves
+// them back from one with -replay so a run needs no backend at all.
+//
+// Replayed responses are matched by operation rather than by exact
+// request, since table names and signatures differ from run to run:
+// each request gets the next unused response recorded for the same
+// method, service and operation.
+type cassette struct {
+	mu      sync.Mutex
+	file    *os.File
+	replay  map[string][]interaction
+	path    string
+	written int
+}
+
+// openCassette opens -record or -replay; with neither it returns nil,
+// which wraps nothing.
+func openCassette(flags *workload.Config) (*cassette, error) {
+	switch {
+	case flags.RecordPath != "":
+		f, err := os.Create(flags.RecordPath)
+		if err != nil {
+			return nil, err
+		}
+		return &cassette{file: f, path: flags.RecordPath}, nil
+	case flags.ReplayPath != "":
+		return loadCassette(flags.ReplayPath)
+	default:
+		return nil, nil
+	}
+}
+
+func loadCassette(path string) (*cassette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &cassette{replay: make(map[string][]interaction), path: path}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var in interaction
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		c.replay[in.Key] = append(c.replay[in.Key], in)
+	}
+	return c, scanner.Err()
+}
+
+// wrap returns next recording to, or replaced by, the cassette.
+func (c *cassette) wrap(next aws.HTTPClient) aws.HTTPClient {
+	if c == nil {
+		return next
+	}
+	if c.replay != nil {
+		return cassetteFunc(c.play)
+	}
+	return cassetteFunc(func(req *http.Request) (*http.Response, error) {
+		return c.record(next, req)
+	})
+}
+
+// Close finishes a recording.
+func (c *cassette) Close(logger *obsv.Logger) {
+	if c == nil || c.file == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.file.Close(); err != nil {
+		logger.Operationf("Failed to close cassette %s: %v", c.path, err)
+		return
+	}
+	logger.Operationf("Recorded %d interactions to %s", c.written, c.path)
+}
+
+type cassetteFunc func(*http.Request) (*http.Response, error)
+
+func (f cassetteFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func (c *cassette) record(next aws.HTTPClient, req *http.Request) (*http.Response, error) {
+	body, err := peekBody(req)
+	if err != nil {
+		return nil, err
+	}
+	requestBody, _ := obsv.Redact(string(body))
+	in := interaction{Key: interactionKey(req, body), Request: recordedRequest{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Header: redactHeader(req.Header),
+		Body:   requestBody,
+	}}
+
+	resp, err := next.Do(req)
+	if err != nil {
+		in.Error, _ = obsv.Redact(err.Error())
+		c.write(in)
+		return resp, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return resp, err
+	}
+	in.Response = recordedResponse{Status: resp.StatusCode, Header: redactHeader(resp.Header), Body: data}
+	if redacted, n := obsv.Redact(string(data)); n > 0 {
+		// The replayed body no longer matches what was sent for it.
+		in.Response.Body = []byte(redacted)
+		in.Response.Header.Del("Content-Length")
+		in.Response.Header.Del("X-Amz-Crc32")
+	}
+	c.write(in)
+	return resp, nil
+}
+
+// redactHeader returns a copy of h fit for a cassette.
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, values := range h {
+		for i, v := range values {
+			values[i], _ = obsv.Redact(v)
+		}
+	}
+	for _, name := range redactedHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, obsv.RedactionMarker)
+		}
+	}
+	return h
+}
+
+// redactURL returns u fit for a cassette, scrubbing presigned credentials
+// and any secret passed as a query parameter.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for name, values := range query {
+		for i, v := range values {
+			if isRedactedHeader(name) {
+				values[i] = obsv.RedactionMarker
+				changed = true
+			} else if redacted, n := obsv.Redact(v); n > 0 {
+				values[i] = redacted
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+func isRedactedHeader(name string) bool {
+	for _, h := range redactedHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cassette) write(in interaction) {
+	line, err := json.Marshal(in)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err == nil {
+		c.written++
+	}
+}
+
+func (c *cassette) play(req *http.Request) (*http.Response, error) {
+	body, err := peekBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := interactionKey(req, body)
+	c.mu.Lock()
+	queue := c.replay[key]
+	if len(queue) == 0 {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("cassette %s has no more recorded responses for %s", c.path, key)
+	}
+	in := queue[0]
+	c.replay[key] = queue[1:]
+	c.mu.Unlock()
+
+	if in.Error != "" {
+		return nil, errors.New("replayed: " + in.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Response.Header,
+		Body:          io.NopCloser(bytes.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// interactionKey names the operation a request performs: the method,
+// the service from the signature scope, and the X-Amz-Target for JSON
+// protocols, the Action for query protocols or the query keys for REST.
+func interactionKey(req *http.Request, body []byte) string {
+	service := signedService(req.Header.Get("Authorization"))
+	if target := req.Header.Get("X-Amz-Target"); target != "" {
+		return req.Method + " " + service + " " + target
+	}
+	query := req.URL.Query()
+	if action := query.Get("Action"); action != "" {
+		return req.Method + " " + service + " " + action
+	}
+	if action := formAction(req, body); action != "" {
+		return req.Method + " " + service + " " + action
+	}
+	if service == "" {
+		// Unsigned requests are LocalStack's own endpoints; the path is stable.
+		return req.Method + " " + req.URL.Path
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return req.Method + " " + service + " ?" + strings.Join(keys, "&")
+}
+
+// signedService returns the service from a SigV4 or SigV4a credential scope.
+func signedService(authorization string) string {
+	_, rest, ok := strings.Cut(authorization, "Credential=")
+	if !ok {
+		return ""
+	}
+	scope, _, _ := strings.Cut(rest, ",")
+	parts := strings.Split(scope, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[len(parts)-2]
+}
+
+// formAction reads Action from a form-encoded query protocol body.
+func formAction(req *http.Request, body []byte) string {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return ""
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get("Action")
+}
+
+// peekBody returns a request body of known length up to
+// maxRecordedRequestBody, putting an identical one back for sending.
+// Larger and streamed bodies are left alone and reported as empty; a
+// body with no length set counts as streamed.
+func peekBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength <= 0 || req.ContentLength > maxRecordedRequestBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
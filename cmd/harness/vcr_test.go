@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/Lou-Varndell/files/workload"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// cassetteClient returns a DynamoDB client for endpoint using the
+// cassette opened from args.
+func cassetteClient(t *testing.T, endpoint string, args ...string) (*dynamodb.Client, *cassette, *obsv.Logger) {
+	t.Helper()
+	cfg, vcr, logger := cassetteConfig(t, endpoint, args...)
+	return dynamodb.NewFromConfig(cfg), vcr, logger
+}
+
+// cassetteConfig loads a config for endpoint using the cassette opened
+// from args.
+func cassetteConfig(t *testing.T, endpoint string, args ...string) (aws.Config, *cassette, *obsv.Logger) {
+	t.Helper()
+	flags, err := workload.ParseFlags("harness", append([]string{"-endpoint", endpoint, "-quiet"}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcr, err := openCassette(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := &obsv.Logger{Verbosity: flags.Verbosity}
+	loader, err := newSDKLoader(flags, logger, obsv.NewStats(), vcr.wrap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loader.load(context.Background(), ddbtest.TestCredentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, vcr, logger
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Write([]byte(`{"TableNames":["Recorded"]}`))
+	}))
+
+	client, vcr, logger := cassetteClient(t, srv.URL, "-record", path)
+	if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	vcr.Close(logger)
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "Signature=") || !strings.Contains(string(data), "DynamoDB_20120810.ListTables") {
+		t.Errorf("cassette should hold the unsigned operation:\n%s", data)
+	}
+
+	client, _, _ = cassetteClient(t, srv.URL, "-replay", path)
+	out, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if len(out.TableNames) != 1 || out.TableNames[0] != "Recorded" || calls != 1 {
+		t.Errorf("replay returned %v after %d backend calls, want [Recorded] after 1", out.TableNames, calls)
+	}
+
+	if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err == nil {
+		t.Error("replay served more responses than were recorded")
+	}
+}
+
+func TestCassetteRedactsCredentials(t *testing.T) {
+	const (
+		secret = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+		token  = "IQoJb3JpZ2luX2VjEJr//////////wEaCXVzLWVhc3QtMSJHMEUCIQDexample+token="
+	)
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>` +
+			`<Credentials><AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>` + secret + `</SecretAccessKey>` +
+			`<SessionToken>` + token + `</SessionToken><Expiration>2030-01-01T00:00:00Z</Expiration></Credentials>` +
+			`</AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer srv.Close()
+
+	cfg, vcr, logger := cassetteConfig(t, srv.URL, "-record", path)
+	in := &sts.AssumeRoleInput{RoleArn: aws.String("arn:aws:iam::000000000000:role/r"), RoleSessionName: aws.String("s")}
+	if _, err := sts.NewFromConfig(cfg).AssumeRole(context.Background(), in); err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	vcr.Close(logger)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{secret, token[:12]} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("cassette holds %q:\n%s", leaked, data)
+		}
+	}
+
+	cfg, _, _ = cassetteConfig(t, srv.URL, "-replay", path)
+	out, err := sts.NewFromConfig(cfg).AssumeRole(context.Background(), in)
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if got := aws.ToString(out.Credentials.SecretAccessKey); got != obsv.RedactionMarker {
+		t.Errorf("replayed secret = %q, want %q", got, obsv.RedactionMarker)
+	}
+}
+
+func TestPeekBody(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		body   io.Reader
+		length int64
+		want   string
+	}{
+		{name: "known length", body: strings.NewReader("{}"), length: 2, want: "{}"},
+		{name: "no body", body: http.NoBody},
+		{name: "unknown length", body: strings.NewReader("streamed"), length: 0},
+		{name: "chunked", body: strings.NewReader("streamed"), length: -1},
+		{name: "too large", body: strings.NewReader("large"), length: maxRecordedRequestBody + 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "http://localhost/", tc.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.ContentLength = tc.length
+			body := req.Body
+			got, err := peekBody(req)
+			if err != nil || string(got) != tc.want {
+				t.Errorf("peekBody = %q, %v, want %q", got, err, tc.want)
+			}
+			if tc.want == "" && req.Body != body {
+				t.Error("peekBody replaced a body it should have left alone")
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+// Package credlog provides credential providers that log what they do:
+// a wrapper that reports each refresh and the time left on the current
+// credentials, a Secrets Manager keypair that follows rotation, and an
+// assumed role with session tags.
+package credlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// SessionTag is a key/value pair attached to assumed-role sessions.
+type SessionTag struct {
+	Key   string
+	Value string
+}
+
+// Role describes the role AssumeRoleProvider assumes and the session it
+// asks for.
+type Role struct {
+	ARN         string
+	SessionName string
+	Duration    time.Duration
+	// Tags are attached to the session; those named in TransitiveTagKeys
+	// carry through role chaining.
+	Tags              []SessionTag
+	TransitiveTagKeys []string
+}
+
+// AssumeRoleProvider returns a provider that assumes role through client,
+// attaching the session tags and marking the transitive ones.
+func AssumeRoleProvider(client stscreds.AssumeRoleAPIClient, role Role) aws.CredentialsProvider {
+	tags := make([]ststypes.Tag, len(role.Tags))
+	for i, t := range role.Tags {
+		tags[i] = ststypes.Tag{Key: aws.String(t.Key), Value: aws.String(t.Value)}
+	}
+
+	return stscreds.NewAssumeRoleProvider(client, role.ARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = role.SessionName
+		o.Duration = role.Duration
+		o.Tags = tags
+		o.TransitiveTagKeys = role.TransitiveTagKeys
+	})
+}
+
+// DescribeSessionTags formats tags for the credential log, flagging the
+// transitive ones.
+func DescribeSessionTags(tags []SessionTag, transitive []string) string {
+	isTransitive := make(map[string]bool, len(transitive))
+	for _, k := range transitive {
+		isTransitive[k] = true
+	}
+
+	parts := make([]string, len(tags))
+	for i, t := range tags {
+		parts[i] = t.Key + "=" + t.Value
+		if isTransitive[t.Key] {
+			parts[i] += " (transitive)"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// secretKeypair is the JSON shape expected in the secret string.
+type secretKeypair struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken"`
+}
+
+// SecretCredentialsProvider reads a static keypair from a Secrets Manager
+// secret using separate bootstrap credentials. The returned credentials
+// expire after Refresh so the cache re-reads the secret and picks up a
+// rotation.
+type SecretCredentialsProvider struct {
+	Client   *secretsmanager.Client
+	SecretID string
+	Refresh  time.Duration
+	Logger   *obsv.Logger
+
+	mu        sync.Mutex
+	versionID string
+}
+
+func (p *SecretCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	out, err := p.Client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &p.SecretID})
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("read secret %s: %w", p.SecretID, err)
+	}
+
+	var kp secretKeypair
+	if err := json.Unmarshal([]byte(aws.ToString(out.SecretString)), &kp); err != nil {
+		return aws.Credentials{}, fmt.Errorf("secret %s is not a JSON keypair: %w", p.SecretID, err)
+	}
+	if kp.AccessKeyID == "" || kp.SecretAccessKey == "" {
+		return aws.Credentials{}, fmt.Errorf("secret %s has no AccessKeyId or SecretAccessKey", p.SecretID)
+	}
+
+	version := aws.ToString(out.VersionId)
+	p.mu.Lock()
+	previous := p.versionID
+	p.versionID = version
+	p.mu.Unlock()
+	if previous != "" && previous != version {
+		p.Logger.Credentialf("Secret %s rotated: version %s -> %s", p.SecretID, previous, version)
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     kp.AccessKeyID,
+		SecretAccessKey: kp.SecretAccessKey,
+		SessionToken:    kp.SessionToken,
+		Source:          "SecretsManager:" + p.SecretID,
+		CanExpire:       true,
+		Expires:         time.Now().Add(p.Refresh),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package credlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+func TestDescribeSessionTags(t *testing.T) {
+	tags := []SessionTag{{"team", "storage"}, {"env", "dev"}}
+	for _, tc := range []struct {
+		name       string
+		tags       []SessionTag
+		transitive []string
+		want       string
+	}{
+		{"none", nil, nil, ""},
+		{"plain", tags, nil, "team=storage, env=dev"},
+		{"transitive", tags, []string{"env"}, "team=storage, env=dev (transitive)"},
+	} {
+		if got := DescribeSessionTags(tc.tags, tc.transitive); got != tc.want {
+			t.Errorf("%s: DescribeSessionTags = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// assumeRoleRecorder answers AssumeRole with fixed credentials and
+// keeps the request.
+type assumeRoleRecorder struct {
+	in *sts.AssumeRoleInput
+}
+
+func (r *assumeRoleRecorder) AssumeRole(_ context.Context, in *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	r.in = in
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("ASIAEXAMPLE"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestAssumeRoleProvider(t *testing.T) {
+	client := &assumeRoleRecorder{}
+	provider := AssumeRoleProvider(client, Role{
+		ARN:               "arn:aws:iam::000000000000:role/harness",
+		SessionName:       "harness",
+		Duration:          15 * time.Minute,
+		Tags:              []SessionTag{{"team", "storage"}},
+		TransitiveTagKeys: []string{"team"},
+	})
+	creds, err := provider.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "ASIAEXAMPLE" {
+		t.Errorf("retrieved %s, want the assumed role's credentials", creds.AccessKeyID)
+	}
+
+	in := client.in
+	if aws.ToString(in.RoleArn) != "arn:aws:iam::000000000000:role/harness" || aws.ToString(in.RoleSessionName) != "harness" ||
+		aws.ToInt32(in.DurationSeconds) != 900 {
+		t.Errorf("assumed %s as %s for %ds, want the role as harness for 900s",
+			aws.ToString(in.RoleArn), aws.ToString(in.RoleSessionName), aws.ToInt32(in.DurationSeconds))
+	}
+	if len(in.Tags) != 1 || aws.ToString(in.Tags[0].Key) != "team" || aws.ToString(in.Tags[0].Value) != "storage" ||
+		len(in.TransitiveTagKeys) != 1 || in.TransitiveTagKeys[0] != "team" {
+		t.Errorf("session tags %+v, transitive %v; want team=storage, transitive", in.Tags, in.TransitiveTagKeys)
+	}
+}
+
+func TestSecretCredentialsProvider(t *testing.T) {
+	type version struct{ id, secret string }
+	for _, tc := range []struct {
+		name     string
+		versions []version
+		err      string
+		rotated  int
+	}{
+		{name: "keypair", versions: []version{{"v1", `{"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret"}`}}},
+		{name: "unchanged", versions: []version{
+			{"v1", `{"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret"}`},
+			{"v1", `{"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret"}`},
+		}},
+		{name: "rotated", versions: []version{
+			{"v1", `{"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret"}`},
+			{"v2", `{"AccessKeyId":"AKIAEXAMPLE","SecretAccessKey":"secret2"}`},
+		}, rotated: 1},
+		{name: "not json", versions: []version{{"v1", "AKIAEXAMPLE:secret"}}, err: "not a JSON keypair"},
+		{name: "no secret key", versions: []version{{"v1", `{"AccessKeyId":"AKIAEXAMPLE"}`}}, err: "no AccessKeyId or SecretAccessKey"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				v := tc.versions[min(calls, len(tc.versions)-1)]
+				calls++
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+				json.NewEncoder(w).Encode(map[string]string{"VersionId": v.id, "SecretString": v.secret})
+			}))
+			defer srv.Close()
+
+			sink := &ddbtest.RecordingSink{}
+			logger := &obsv.Logger{}
+			logger.AddSink(sink)
+			p := &SecretCredentialsProvider{
+				Client: secretsmanager.NewFromConfig(aws.Config{
+					Region:       "us-east-1",
+					BaseEndpoint: aws.String(srv.URL),
+					Credentials:  ddbtest.TestCredentials,
+				}),
+				SecretID: "harness/keypair",
+				Refresh:  time.Minute,
+				Logger:   logger,
+			}
+
+			for i := range tc.versions {
+				creds, err := p.Retrieve(context.Background())
+				if tc.err != "" {
+					if err == nil || !strings.Contains(err.Error(), tc.err) {
+						t.Fatalf("Retrieve error %v, want %q", err, tc.err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if creds.AccessKeyID != "AKIAEXAMPLE" || !creds.CanExpire || time.Until(creds.Expires) > time.Minute {
+					t.Errorf("retrieval %d: %s expiring %v, want the secret's keypair to expire within the refresh interval",
+						i, creds.AccessKeyID, creds.Expires)
+				}
+			}
+			if n := sink.Count(obsv.CategoryCredentials, "rotated"); n != tc.rotated {
+				t.Errorf("logged %d rotations, want %d", n, tc.rotated)
+			}
+		})
+	}
+}
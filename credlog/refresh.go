@@ -0,0 +1,95 @@
+package credlog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// RefreshLoggingProvider logs only when credentials are refreshed
+// and tracks them for TTL logging.
+type RefreshLoggingProvider struct {
+	Provider aws.CredentialsProvider
+	Logger   *obsv.Logger
+	// Stats, if set, counts refreshes and failures.
+	Stats *obsv.Stats
+	// OnRefresh, if set, is called after each logged refresh.
+	OnRefresh func(aws.Credentials)
+
+	mu        sync.Mutex
+	lastCreds aws.Credentials
+	seen      bool
+}
+
+func (r *RefreshLoggingProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := r.Provider.Retrieve(ctx)
+	if err != nil {
+		r.Logger.Credentialf("failed to retrieve: %v", err)
+		if r.Stats != nil {
+			r.Stats.CredentialFailed()
+		}
+		return creds, &obsv.HarnessError{Class: obsv.ClassCredentials, Op: "RetrieveCredentials", Err: err}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.seen ||
+		creds.AccessKeyID != r.lastCreds.AccessKeyID ||
+		creds.SecretAccessKey != r.lastCreds.SecretAccessKey ||
+		creds.SessionToken != r.lastCreds.SessionToken {
+
+		ttl := "N/A"
+		if !creds.Expires.IsZero() {
+			ttl = time.Until(creds.Expires).String()
+		}
+
+		r.Logger.Credentialf("REFRESHED: AccessKey=%s, ExpiresIn=%s, SessionTokenPresent=%v",
+			creds.AccessKeyID, ttl, creds.SessionToken != "")
+
+		r.lastCreds = creds
+		r.seen = true
+
+		if r.Stats != nil {
+			r.Stats.CredentialRefreshed(creds.Expires)
+		}
+		if r.OnRefresh != nil {
+			r.OnRefresh(creds)
+		}
+	}
+
+	return creds, nil
+}
+
+// StartTTLLogger periodically logs how long until creds expire
+func (r *RefreshLoggingProvider) StartTTLLogger(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.mu.Lock()
+				creds := r.lastCreds
+				r.mu.Unlock()
+
+				if creds.AccessKeyID == "" {
+					continue // not retrieved yet
+				}
+
+				if creds.Expires.IsZero() {
+					r.Logger.Credentialf("TTL check: permanent credentials, no expiration")
+				} else {
+					remaining := time.Until(creds.Expires)
+					r.Logger.Credentialf("TTL check: %s remaining until expiration", remaining)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,181 @@
+package ddbtest
+
+import (
+	"context"
+	"encoding/base64"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Cache is a read-through GetItem cache in front of an API, a stand-in
+// for DAX. An item read through it is served from memory for TTL, absent
+// items included, unless a write through the cache replaces it first.
+// Strongly consistent and projected reads go straight to the API.
+type Cache struct {
+	API
+	ttl   time.Duration
+	stats *obsv.Stats
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// keyNames holds each table's key attributes, learned from the keys
+	// of its GetItem calls, to find the entry a written item replaces.
+	keyNames map[string][]string
+	// version counts invalidations, so a read that raced a write does
+	// not cache what it read.
+	version uint64
+}
+
+type cacheEntry struct {
+	item    map[string]types.AttributeValue
+	expires time.Time
+}
+
+// NewCache returns a cache in front of api that keeps items for ttl and
+// counts its hits and misses in stats.
+func NewCache(api API, ttl time.Duration, stats *obsv.Stats) *Cache {
+	return &Cache{
+		API:      api,
+		ttl:      ttl,
+		stats:    stats,
+		entries:  make(map[string]cacheEntry),
+		keyNames: make(map[string][]string),
+	}
+}
+
+func (c *Cache) GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	key, ok := cacheKey(aws.ToString(in.TableName), in.Key)
+	if !ok || aws.ToBool(in.ConsistentRead) || in.ProjectionExpression != nil || in.AttributesToGet != nil {
+		return c.API.GetItem(ctx, in, optFns...)
+	}
+
+	c.mu.Lock()
+	entry, hit := c.entries[key]
+	if hit && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		hit = false
+	}
+	version := c.version
+	c.mu.Unlock()
+	c.stats.CacheLookup(hit)
+	if hit {
+		return &dynamodb.GetItemOutput{Item: maps.Clone(entry.item)}, nil
+	}
+
+	out, err := c.API.GetItem(ctx, in, optFns...)
+	if err != nil {
+		return out, err
+	}
+	c.mu.Lock()
+	if c.version == version {
+		table := aws.ToString(in.TableName)
+		if _, ok := c.keyNames[table]; !ok {
+			c.keyNames[table] = slices.Sorted(maps.Keys(in.Key))
+		}
+		c.entries[key] = cacheEntry{item: maps.Clone(out.Item), expires: time.Now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	return out, nil
+}
+
+func (c *Cache) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	defer c.invalidate(aws.ToString(in.TableName), in.Item)
+	return c.API.PutItem(ctx, in, optFns...)
+}
+
+func (c *Cache) BatchWriteItem(ctx context.Context, in *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	defer func() {
+		for table, requests := range in.RequestItems {
+			for _, r := range requests {
+				switch {
+				case r.PutRequest != nil:
+					c.invalidate(table, r.PutRequest.Item)
+				case r.DeleteRequest != nil:
+					c.invalidate(table, r.DeleteRequest.Key)
+				}
+			}
+		}
+	}()
+	return c.API.BatchWriteItem(ctx, in, optFns...)
+}
+
+func (c *Cache) TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	defer func() {
+		for _, action := range in.TransactItems {
+			switch {
+			case action.Put != nil:
+				c.invalidate(aws.ToString(action.Put.TableName), action.Put.Item)
+			case action.Delete != nil:
+				c.invalidate(aws.ToString(action.Delete.TableName), action.Delete.Key)
+			case action.Update != nil:
+				c.invalidate(aws.ToString(action.Update.TableName), action.Update.Key)
+			}
+		}
+	}()
+	return c.API.TransactWriteItems(ctx, in, optFns...)
+}
+
+func (c *Cache) DeleteTable(ctx context.Context, in *dynamodb.DeleteTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	defer func() {
+		table := aws.ToString(in.TableName)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.version++
+		for key := range c.entries {
+			if strings.HasPrefix(key, table+"\x00") {
+				delete(c.entries, key)
+			}
+		}
+		delete(c.keyNames, table)
+	}()
+	return c.API.DeleteTable(ctx, in, optFns...)
+}
+
+// invalidate drops the entry for the item written to table. It runs
+// whether or not the write succeeded, since a failed call may still
+// have been applied.
+func (c *Cache) invalidate(table string, item map[string]types.AttributeValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version++
+	names, ok := c.keyNames[table]
+	if !ok {
+		return
+	}
+	key := make(map[string]types.AttributeValue, len(names))
+	for _, name := range names {
+		key[name] = item[name]
+	}
+	if k, ok := cacheKey(table, key); ok {
+		delete(c.entries, k)
+	}
+}
+
+// cacheKey encodes the table and key attributes as a map key, or
+// reports false for a key the cache cannot encode.
+func cacheKey(table string, key map[string]types.AttributeValue) (string, bool) {
+	var b strings.Builder
+	b.WriteString(table)
+	for _, name := range slices.Sorted(maps.Keys(key)) {
+		b.WriteString("\x00" + name + "=")
+		switch v := key[name].(type) {
+		case *types.AttributeValueMemberS:
+			b.WriteString("S:" + v.Value)
+		case *types.AttributeValueMemberN:
+			b.WriteString("N:" + v.Value)
+		case *types.AttributeValueMemberB:
+			b.WriteString("B:" + base64.StdEncoding.EncodeToString(v.Value))
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,89 @@
+package ddbtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+	if _, err := m.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stats := obsv.NewStats()
+	c := NewCache(m, time.Hour, stats)
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	put := func(name string) {
+		t.Helper()
+		item := map[string]types.AttributeValue{"ID": key["ID"], "Name": &types.AttributeValueMemberS{Value: name}}
+		if _, err := c.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := func() string {
+		t.Helper()
+		out, err := c.GetItem(ctx, &dynamodb.GetItemInput{TableName: &table, Key: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := out.Item["Name"].(*types.AttributeValueMemberS); ok {
+			return v.Value
+		}
+		return ""
+	}
+
+	put("a")
+	if got := name(); got != "a" {
+		t.Fatalf("first read got %q, want a", got)
+	}
+	// Behind the cache's back, so a cached read still sees the old item.
+	m.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: map[string]types.AttributeValue{
+		"ID": key["ID"], "Name": &types.AttributeValueMemberS{Value: "direct"}}})
+	if got := name(); got != "a" {
+		t.Errorf("cached read got %q, want a", got)
+	}
+	put("b")
+	if got := name(); got != "b" {
+		t.Errorf("read after a write through the cache got %q, want b", got)
+	}
+
+	var report strings.Builder
+	stats.Report(&report)
+	if !strings.Contains(report.String(), "Read cache: 1 hits, 2 misses") {
+		t.Errorf("summary does not show 1 hit and 2 misses:\n%s", report.String())
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+	if _, err := m.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache(m, time.Millisecond, obsv.NewStats())
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	if out, _ := c.GetItem(ctx, &dynamodb.GetItemInput{TableName: &table, Key: key}); out.Item != nil {
+		t.Fatal("absent item found")
+	}
+	m.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: key})
+	time.Sleep(5 * time.Millisecond)
+	if out, _ := c.GetItem(ctx, &dynamodb.GetItemInput{TableName: &table, Key: key}); out.Item == nil {
+		t.Error("read after the TTL still served the cached absence")
+	}
+}
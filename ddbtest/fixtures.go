@@ -0,0 +1,15 @@
+package ddbtest
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// Offline points clients at an address nothing listens on, so a test
+// that reaches the network fails rather than finding a backend.
+const Offline = "http://127.0.0.1:1"
+
+// TestCredentials is the LocalStack test keypair tests sign with.
+var TestCredentials = credentials.StaticCredentialsProvider{
+	Value: aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"},
+}
@@ -0,0 +1,62 @@
+package ddbtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/localstack"
+)
+
+// defaultTestImage is the LocalStack image NewLocalStack runs unless
+// LOCALSTACK_IMAGE names another.
+const defaultTestImage = "localstack/localstack:3.8"
+
+// NewLocalStack starts a LocalStack container for t and returns its
+// endpoint. The container is removed when the test ends, and the test is
+// skipped when no container runtime is available.
+func NewLocalStack(t *testing.T) string {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	image := os.Getenv("LOCALSTACK_IMAGE")
+	if image == "" {
+		image = defaultTestImage
+	}
+	ctr, endpoint, err := StartLocalStack(context.Background(), image)
+	if err != nil {
+		t.Fatalf("start %s: %v", image, err)
+	}
+	testcontainers.CleanupContainer(t, ctr)
+	return endpoint
+}
+
+// StartLocalStack runs image in a container, waits until its health
+// endpoint reports ready and returns the container with its edge
+// endpoint URL.
+func StartLocalStack(ctx context.Context, image string) (*localstack.LocalStackContainer, string, error) {
+	ctr, err := localstack.Run(ctx, image)
+	if err != nil {
+		if ctr != nil {
+			_ = testcontainers.TerminateContainer(ctr)
+		}
+		return nil, "", err
+	}
+	endpoint, err := ctr.PortEndpoint(ctx, "4566/tcp", "http")
+	if err != nil {
+		_ = testcontainers.TerminateContainer(ctr)
+		return nil, "", err
+	}
+	return ctr, endpoint, nil
+}
+
+// StopLocalStack removes a container started by StartLocalStack.
+func StopLocalStack(ctr *localstack.LocalStackContainer, logger *obsv.Logger) {
+	if err := testcontainers.TerminateContainer(ctr); err != nil {
+		logger.Operationf("Failed to remove LocalStack container: %v", err)
+		return
+	}
+	logger.Operationf("LocalStack container removed")
+}
@@ -0,0 +1,455 @@
+// Package ddbtest is a DynamoDB test kit: the client subset the harness
+// drives, an in-process backend that implements it, and snapshots that
+// carry tables and items from one run to the next.
+package ddbtest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// API is the subset of the DynamoDB client the harness uses, so
+// -backend=memory can stand in for a real endpoint.
+type API interface {
+	CreateTable(ctx context.Context, in *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DescribeTable(ctx context.Context, in *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	DeleteTable(ctx context.Context, in *dynamodb.DeleteTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+	ListTables(ctx context.Context, in *dynamodb.ListTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error)
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	BatchWriteItem(ctx context.Context, in *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	BatchGetItem(ctx context.Context, in *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	UpdateContinuousBackups(ctx context.Context, in *dynamodb.UpdateContinuousBackupsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error)
+	Scan(ctx context.Context, in *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// Memory is an in-process DynamoDB holding tables in maps. It knows
+// only simple hash-key tables and the operations in API, and fails
+// with the same exception types as the service so error handling and
+// classification behave as they would against a real endpoint.
+type Memory struct {
+	mu     sync.Mutex
+	tables map[string]*memoryTable
+	// tokens holds the TransactWriteItems calls applied in the last
+	// idempotencyWindow, by ClientRequestToken.
+	tokens map[string]appliedToken
+}
+
+// appliedToken is what a ClientRequestToken was first used for.
+type appliedToken struct {
+	request string
+	expires time.Time
+}
+
+// idempotencyWindow is how long DynamoDB remembers a ClientRequestToken.
+const idempotencyWindow = 10 * time.Minute
+
+type memoryTable struct {
+	description types.TableDescription
+	hashKey     string
+	items       map[string]map[string]types.AttributeValue
+}
+
+// NewMemory returns an empty in-process DynamoDB.
+func NewMemory() *Memory {
+	return &Memory{tables: make(map[string]*memoryTable), tokens: make(map[string]appliedToken)}
+}
+
+func (m *Memory) CreateTable(_ context.Context, in *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	name := aws.ToString(in.TableName)
+	var hashKey string
+	for _, k := range in.KeySchema {
+		switch k.KeyType {
+		case types.KeyTypeHash:
+			hashKey = aws.ToString(k.AttributeName)
+		default:
+			return nil, validationError("the memory backend supports hash keys only")
+		}
+	}
+	if name == "" || hashKey == "" {
+		return nil, validationError("TableName and a HASH key are required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tables[name]; ok {
+		return nil, &types.ResourceInUseException{Message: aws.String("Table already exists: " + name)}
+	}
+	t := &memoryTable{
+		description: types.TableDescription{
+			TableName:            &name,
+			TableStatus:          types.TableStatusActive,
+			KeySchema:            in.KeySchema,
+			AttributeDefinitions: in.AttributeDefinitions,
+			CreationDateTime:     aws.Time(time.Now()),
+			StreamSpecification:  in.StreamSpecification,
+		},
+		hashKey: hashKey,
+		items:   make(map[string]map[string]types.AttributeValue),
+	}
+	if in.BillingMode != "" {
+		t.description.BillingModeSummary = &types.BillingModeSummary{BillingMode: in.BillingMode}
+	}
+	m.tables[name] = t
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe()}, nil
+}
+
+func (m *Memory) DescribeTable(_ context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+func (m *Memory) DeleteTable(_ context.Context, in *dynamodb.DeleteTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(m.tables, aws.ToString(in.TableName))
+	desc := t.describe()
+	desc.TableStatus = types.TableStatusDeleting
+	return &dynamodb.DeleteTableOutput{TableDescription: desc}, nil
+}
+
+func (m *Memory) ListTables(_ context.Context, in *dynamodb.ListTablesInput, _ ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.tables))
+	for name := range m.tables {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+
+	start := 0
+	if in.ExclusiveStartTableName != nil {
+		start = sort.SearchStrings(names, *in.ExclusiveStartTableName)
+		if start < len(names) && names[start] == *in.ExclusiveStartTableName {
+			start++
+		}
+	}
+	names = names[start:]
+
+	out := &dynamodb.ListTablesOutput{}
+	if limit := int(aws.ToInt32(in.Limit)); limit > 0 && limit < len(names) {
+		names = names[:limit]
+		out.LastEvaluatedTableName = &names[limit-1]
+	}
+	out.TableNames = names
+	return out, nil
+}
+
+func (m *Memory) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.key(in.Item)
+	if err != nil {
+		return nil, err
+	}
+	t.items[key] = maps.Clone(in.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// BatchWriteItem applies every put and delete request, or none of them
+// if any is invalid. The memory backend never throttles, so nothing is
+// left unprocessed.
+func (m *Memory) BatchWriteItem(_ context.Context, in *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type write struct {
+		t    *memoryTable
+		key  string
+		item map[string]types.AttributeValue
+	}
+	var writes []write
+	for name, requests := range in.RequestItems {
+		t, err := m.table(&name)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range requests {
+			var w write
+			var err error
+			switch {
+			case r.PutRequest != nil:
+				w.key, err = t.key(r.PutRequest.Item)
+				w.item = r.PutRequest.Item
+			case r.DeleteRequest != nil:
+				w.key, err = t.key(r.DeleteRequest.Key)
+			default:
+				err = validationError("a write request must be a put or a delete")
+			}
+			if err != nil {
+				return nil, err
+			}
+			w.t = t
+			writes = append(writes, w)
+		}
+	}
+	if len(writes) == 0 || len(writes) > 25 {
+		return nil, validationError("a BatchWriteItem call takes between 1 and 25 write requests")
+	}
+
+	for _, w := range writes {
+		if w.item == nil {
+			delete(w.t.items, w.key)
+		} else {
+			w.t.items[w.key] = maps.Clone(w.item)
+		}
+	}
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+// TransactWriteItems applies every put and delete, or none of them if
+// any is invalid. A call repeating the ClientRequestToken of one applied
+// in the last ten minutes succeeds without applying it again, as the
+// service does, and fails if its request differs.
+func (m *Memory) TransactWriteItems(_ context.Context, in *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(in.TransactItems) == 0 || len(in.TransactItems) > 100 {
+		return nil, validationError("a TransactWriteItems call takes between 1 and 100 actions")
+	}
+	for t, applied := range m.tokens {
+		if time.Now().After(applied.expires) {
+			delete(m.tokens, t)
+		}
+	}
+	token := aws.ToString(in.ClientRequestToken)
+	request, err := json.Marshal(encodeTransaction(in.TransactItems))
+	if err != nil {
+		return nil, err
+	}
+	if applied, ok := m.tokens[token]; ok && token != "" {
+		if applied.request != string(request) {
+			return nil, &types.IdempotentParameterMismatchException{
+				Message: aws.String("the ClientRequestToken was already used for a different request"),
+			}
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+
+	type write struct {
+		t    *memoryTable
+		key  string
+		item map[string]types.AttributeValue
+	}
+	writes := make([]write, 0, len(in.TransactItems))
+	seen := make(map[*memoryTable]map[string]bool)
+	for _, action := range in.TransactItems {
+		var w write
+		var err error
+		switch {
+		case action.Put != nil:
+			if w.t, err = m.table(action.Put.TableName); err == nil {
+				w.key, err = w.t.key(action.Put.Item)
+				w.item = action.Put.Item
+			}
+		case action.Delete != nil:
+			if w.t, err = m.table(action.Delete.TableName); err == nil {
+				w.key, err = w.t.key(action.Delete.Key)
+			}
+		default:
+			err = validationError("the memory backend supports only put and delete actions in a transaction")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[w.t][w.key] {
+			return nil, validationError("transaction request cannot include multiple operations on one item")
+		}
+		if seen[w.t] == nil {
+			seen[w.t] = make(map[string]bool)
+		}
+		seen[w.t][w.key] = true
+		writes = append(writes, w)
+	}
+
+	for _, w := range writes {
+		if w.item == nil {
+			delete(w.t.items, w.key)
+		} else {
+			w.t.items[w.key] = maps.Clone(w.item)
+		}
+	}
+	if token != "" {
+		m.tokens[token] = appliedToken{request: string(request), expires: time.Now().Add(idempotencyWindow)}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// encodeTransaction gives the actions a comparable form, to tell a
+// repeated request from a different one under the same token.
+func encodeTransaction(actions []types.TransactWriteItem) []map[string]any {
+	out := make([]map[string]any, len(actions))
+	for i, a := range actions {
+		switch {
+		case a.Put != nil:
+			out[i] = map[string]any{"put": aws.ToString(a.Put.TableName), "item": encodeItem(a.Put.Item)}
+		case a.Delete != nil:
+			out[i] = map[string]any{"delete": aws.ToString(a.Delete.TableName), "key": encodeItem(a.Delete.Key)}
+		}
+	}
+	return out
+}
+
+func (m *Memory) GetItem(_ context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.key(in.Key)
+	if err != nil {
+		return nil, err
+	}
+	// A missing item is not an error; the output just has no Item.
+	return &dynamodb.GetItemOutput{Item: maps.Clone(t.items[key])}, nil
+}
+
+// BatchGetItem returns every requested item that exists. The memory
+// backend never throttles, so no keys are left unprocessed.
+func (m *Memory) BatchGetItem(_ context.Context, in *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &dynamodb.BatchGetItemOutput{Responses: make(map[string][]map[string]types.AttributeValue)}
+	keys := 0
+	for name, ka := range in.RequestItems {
+		t, err := m.table(&name)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range ka.Keys {
+			key, err := t.key(k)
+			if err != nil {
+				return nil, err
+			}
+			keys++
+			if item, ok := t.items[key]; ok {
+				out.Responses[name] = append(out.Responses[name], maps.Clone(item))
+			}
+		}
+	}
+	if keys == 0 || keys > 100 {
+		return nil, validationError("a BatchGetItem call takes between 1 and 100 keys")
+	}
+	return out, nil
+}
+
+// UpdateContinuousBackups records nothing; the memory backend keeps no
+// history to recover.
+func (m *Memory) UpdateContinuousBackups(_ context.Context, in *dynamodb.UpdateContinuousBackupsInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.table(in.TableName); err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateContinuousBackupsOutput{
+		ContinuousBackupsDescription: &types.ContinuousBackupsDescription{
+			ContinuousBackupsStatus: types.ContinuousBackupsStatusEnabled,
+		},
+	}, nil
+}
+
+// Scan returns items in key order, paging by Limit and ExclusiveStartKey.
+// Filters and projections are not supported.
+func (m *Memory) Scan(_ context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if in.FilterExpression != nil || in.ProjectionExpression != nil {
+		return nil, validationError("the memory backend does not support scan filters or projections")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(t.items))
+	for k := range t.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if in.ExclusiveStartKey != nil {
+		after, err := t.key(in.ExclusiveStartKey)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(keys), func(i int) bool { return keys[i] > after })
+	}
+	keys = keys[start:]
+
+	out := &dynamodb.ScanOutput{}
+	if limit := int(aws.ToInt32(in.Limit)); limit > 0 && limit < len(keys) {
+		keys = keys[:limit]
+		last := t.items[keys[limit-1]]
+		out.LastEvaluatedKey = map[string]types.AttributeValue{t.hashKey: last[t.hashKey]}
+	}
+	for _, k := range keys {
+		out.Items = append(out.Items, maps.Clone(t.items[k]))
+	}
+	out.Count = int32(len(out.Items))
+	out.ScannedCount = out.Count
+	return out, nil
+}
+
+// table looks up name with m.mu held.
+func (m *Memory) table(name *string) (*memoryTable, error) {
+	t, ok := m.tables[aws.ToString(name)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found: Table: " + aws.ToString(name) + " not found")}
+	}
+	return t, nil
+}
+
+func (t *memoryTable) describe() *types.TableDescription {
+	desc := t.description
+	desc.ItemCount = aws.Int64(int64(len(t.items)))
+	return &desc
+}
+
+// key encodes the item's hash key attribute as a map key.
+func (t *memoryTable) key(item map[string]types.AttributeValue) (string, error) {
+	switch v := item[t.hashKey].(type) {
+	case *types.AttributeValueMemberS:
+		return "S:" + v.Value, nil
+	case *types.AttributeValueMemberN:
+		return "N:" + v.Value, nil
+	case *types.AttributeValueMemberB:
+		return "B:" + base64.StdEncoding.EncodeToString(v.Value), nil
+	case nil:
+		return "", validationError(fmt.Sprintf("missing the key %s in the item", t.hashKey))
+	default:
+		return "", validationError(fmt.Sprintf("key %s must be a string, number or binary", t.hashKey))
+	}
+}
+
+func validationError(msg string) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: msg, Fault: smithy.FaultClient}
+}
@@ -0,0 +1,195 @@
+package ddbtest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestMemoryErrors(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+
+	_, err := m.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Errorf("PutItem to a missing table: got %v, want ResourceNotFoundException", err)
+	}
+
+	create := &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}
+	if _, err := m.CreateTable(ctx, create); err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.CreateTable(ctx, create)
+	var inUse *types.ResourceInUseException
+	if !errors.As(err, &inUse) {
+		t.Errorf("second CreateTable: got %v, want ResourceInUseException", err)
+	}
+
+	_, err = m.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &table,
+		Item:      map[string]types.AttributeValue{"Name": &types.AttributeValueMemberS{Value: "x"}},
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("PutItem without the key: got %v, want ValidationException", err)
+	}
+
+	out, err := m.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &table,
+		Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "absent"}},
+	})
+	if err != nil || out.Item != nil {
+		t.Errorf("GetItem of an absent item: got %v, %v; want no item and no error", out.Item, err)
+	}
+}
+
+func TestMemoryBatchWrite(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+	if _, err := m.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	item := func(id string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}
+	}
+
+	_, err := m.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{table: {
+		{PutRequest: &types.PutRequest{Item: item("a")}},
+		{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{}}},
+	}}})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("batch with an item missing its key: got %v, want ValidationException", err)
+	}
+	if out, _ := m.GetItem(ctx, &dynamodb.GetItemInput{TableName: &table, Key: item("a")}); out.Item != nil {
+		t.Error("a rejected batch wrote some of its items")
+	}
+
+	if _, err := m.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{table: {
+		{PutRequest: &types.PutRequest{Item: item("a")}},
+		{PutRequest: &types.PutRequest{Item: item("b")}},
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{table: {
+		{DeleteRequest: &types.DeleteRequest{Key: item("a")}},
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+	desc, _ := m.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &table})
+	if n := aws.ToInt64(desc.Table.ItemCount); n != 1 {
+		t.Errorf("%d items after putting two and deleting one, want 1", n)
+	}
+}
+
+func TestMemoryTransactIdempotent(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+	if _, err := m.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	put := func(id, name string) types.TransactWriteItem {
+		return types.TransactWriteItem{Put: &types.Put{TableName: &table, Item: map[string]types.AttributeValue{
+			"ID":   &types.AttributeValueMemberS{Value: id},
+			"Name": &types.AttributeValueMemberS{Value: name},
+		}}}
+	}
+	name := func(id string) string {
+		out, _ := m.GetItem(ctx, &dynamodb.GetItemInput{TableName: &table, Key: map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}})
+		v, _ := out.Item["Name"].(*types.AttributeValueMemberS)
+		if v == nil {
+			return ""
+		}
+		return v.Value
+	}
+
+	in := &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{put("a", "first"), put("b", "first")}, ClientRequestToken: aws.String("token-1")}
+	if _, err := m.TransactWriteItems(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	// Another writer changes a; replaying the transaction must not undo that.
+	if _, err := m.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: put("a", "second").Put.Item}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.TransactWriteItems(ctx, in); err != nil {
+		t.Fatalf("replayed transaction: %v", err)
+	}
+	if got := name("a"); got != "second" {
+		t.Errorf("item a is %q after replaying its token, want the later write %q kept", got, "second")
+	}
+
+	_, err := m.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:      []types.TransactWriteItem{put("c", "first")},
+		ClientRequestToken: aws.String("token-1"),
+	})
+	var mismatch *types.IdempotentParameterMismatchException
+	if !errors.As(err, &mismatch) {
+		t.Errorf("different request under a used token: got %v, want IdempotentParameterMismatchException", err)
+	}
+	if got := name("c"); got != "" {
+		t.Error("a request rejected for its token was applied")
+	}
+
+	_, err = m.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{put("a", "x"), put("a", "y")}})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("two actions on one item: got %v, want ValidationException", err)
+	}
+}
+
+func TestMemoryBatchGet(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+	if _, err := m.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	key := func(id string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}
+	}
+	if _, err := m.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: key("a")}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := m.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{
+		table: {Keys: []map[string]types.AttributeValue{key("a"), key("b")}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Responses[table]; len(got) != 1 || len(out.UnprocessedKeys) != 0 {
+		t.Errorf("got %d items and %d unprocessed tables, want only item a back", len(got), len(out.UnprocessedKeys))
+	}
+
+	keys := make([]map[string]types.AttributeValue, 101)
+	for i := range keys {
+		keys[i] = key(strconv.Itoa(i))
+	}
+	_, err = m.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{table: {Keys: keys}}})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("101 keys: got %v, want ValidationException", err)
+	}
+}
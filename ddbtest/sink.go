@@ -0,0 +1,33 @@
+package ddbtest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+// RecordingSink keeps every event a logger writes for tests to assert on.
+type RecordingSink struct {
+	mu     sync.Mutex
+	events []obsv.Event
+}
+
+func (s *RecordingSink) Write(e obsv.Event) {
+	s.mu.Lock()
+	s.events = append(s.events, e)
+	s.mu.Unlock()
+}
+
+// Count returns how many events of category contain substr.
+func (s *RecordingSink) Count(category, substr string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, e := range s.events {
+		if e.Category == category && strings.Contains(e.Message, substr) {
+			n++
+		}
+	}
+	return n
+}
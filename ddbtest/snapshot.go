@@ -0,0 +1,268 @@
+package ddbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Snapshot is the on-disk form of a set of tables and their items, as
+// written by -snapshot and read by -restore.
+type Snapshot struct {
+	Taken  time.Time       `json:"taken"`
+	Tables []SnapshotTable `json:"tables"`
+}
+
+// SnapshotTable is one table's key schema and items.
+type SnapshotTable struct {
+	Name       string                      `json:"name"`
+	KeySchema  []types.KeySchemaElement    `json:"keySchema"`
+	Attributes []types.AttributeDefinition `json:"attributes"`
+	Items      []map[string]SnapshotValue  `json:"items"`
+}
+
+// SnapshotValue is an attribute value in DynamoDB's JSON notation, so a
+// snapshot keeps the distinction between strings, numbers and binary.
+// Empty lists, maps and binaries are not preserved.
+type SnapshotValue struct {
+	S    *string                  `json:"S,omitempty"`
+	N    *string                  `json:"N,omitempty"`
+	B    []byte                   `json:"B,omitempty"`
+	BOOL *bool                    `json:"BOOL,omitempty"`
+	NULL *bool                    `json:"NULL,omitempty"`
+	L    []SnapshotValue          `json:"L,omitempty"`
+	M    map[string]SnapshotValue `json:"M,omitempty"`
+	SS   []string                 `json:"SS,omitempty"`
+	NS   []string                 `json:"NS,omitempty"`
+	BS   [][]byte                 `json:"BS,omitempty"`
+}
+
+// Snapshotter dumps tables to snapshot files and restores them through
+// Client, recording every call in Stats.
+type Snapshotter struct {
+	Client   API
+	Stats    *obsv.Stats
+	Logger   *obsv.Logger
+	Timeouts obsv.Timeouts
+}
+
+// Take dumps tables to path. Tables that have since been deleted are
+// left out.
+func (s *Snapshotter) Take(ctx context.Context, path string, tables []string) error {
+	snap := Snapshot{Taken: time.Now().UTC()}
+	for _, name := range tables {
+		table, err := s.DumpTable(ctx, name)
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			s.Logger.Operationf("Snapshot skips deleted table %s", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		snap.Tables = append(snap.Tables, table)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "SaveSnapshot", Err: err}
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "SaveSnapshot", Err: err}
+	}
+	if err := tmp.Close(); err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "SaveSnapshot", Err: err}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "SaveSnapshot", Err: err}
+	}
+	s.Logger.Operationf("Snapshot of %d tables written to %s", len(snap.Tables), path)
+	return nil
+}
+
+// DumpTable reads the schema and every item of the table name.
+func (s *Snapshotter) DumpTable(ctx context.Context, name string) (SnapshotTable, error) {
+	var desc *dynamodb.DescribeTableOutput
+	err := obsv.Timed(ctx, s.Stats, "DescribeTable", s.Timeouts.Control, func(ctx context.Context) error {
+		var err error
+		desc, err = s.Client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &name})
+		return err
+	})
+	if err != nil {
+		return SnapshotTable{}, err
+	}
+	table := SnapshotTable{
+		Name:       name,
+		KeySchema:  desc.Table.KeySchema,
+		Attributes: desc.Table.AttributeDefinitions,
+	}
+
+	scanner := &Scanner{Client: s.Client, Stats: s.Stats, Timeouts: s.Timeouts}
+	err = scanner.Scan(ctx, name, func(item map[string]types.AttributeValue) error {
+		table.Items = append(table.Items, encodeItem(item))
+		return nil
+	})
+	if err != nil {
+		return SnapshotTable{}, err
+	}
+	return table, nil
+}
+
+// Restore recreates the tables in path, loads their items and returns
+// the tables' names. A table that already exists keeps its data, with
+// the snapshot's items written over it.
+func (s *Snapshotter) Restore(ctx context.Context, path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadSnapshot", Err: err}
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "LoadSnapshot", Err: fmt.Errorf("%s: %w", path, err)}
+	}
+
+	var names []string
+	for _, table := range snap.Tables {
+		if err := s.RestoreTable(ctx, table); err != nil {
+			return names, err
+		}
+		names = append(names, table.Name)
+	}
+	s.Logger.Operationf("Restored %d tables from %s (taken %s)", len(snap.Tables), path, snap.Taken.Format(time.RFC3339))
+	return names, nil
+}
+
+// RestoreTable creates table, waits for it and writes its items.
+func (s *Snapshotter) RestoreTable(ctx context.Context, table SnapshotTable) error {
+	err := obsv.Timed(ctx, s.Stats, "CreateTable", s.Timeouts.Control, func(ctx context.Context) error {
+		_, err := s.Client.CreateTable(ctx, &dynamodb.CreateTableInput{
+			TableName:            &table.Name,
+			KeySchema:            table.KeySchema,
+			AttributeDefinitions: table.Attributes,
+			BillingMode:          types.BillingModePayPerRequest,
+		})
+		return err
+	})
+	var inUse *types.ResourceInUseException
+	switch {
+	case errors.As(err, &inUse):
+		s.Logger.Operationf("Table %s already exists; loading snapshot items into it", table.Name)
+	case err != nil:
+		return err
+	}
+
+	err = obsv.Timed(ctx, s.Stats, "WaitTableExists", s.Timeouts.Control, func(ctx context.Context) error {
+		waiter := dynamodb.NewTableExistsWaiter(s.Client, func(o *dynamodb.TableExistsWaiterOptions) {
+			o.MinDelay = 200 * time.Millisecond
+			o.MaxDelay = 2 * time.Second
+		})
+		return waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: &table.Name}, obsv.WaitTimeout(ctx, s.Timeouts.Control))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range table.Items {
+		item := decodeItem(item)
+		err := obsv.Timed(ctx, s.Stats, "PutItem", s.Timeouts.Data, func(ctx context.Context) error {
+			_, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table.Name, Item: item})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	s.Logger.Operationf("Table restored: %s (%d items)", table.Name, len(table.Items))
+	return nil
+}
+
+func encodeItem(item map[string]types.AttributeValue) map[string]SnapshotValue {
+	out := make(map[string]SnapshotValue, len(item))
+	for k, v := range item {
+		out[k] = encodeValue(v)
+	}
+	return out
+}
+
+func encodeValue(av types.AttributeValue) SnapshotValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return SnapshotValue{S: &v.Value}
+	case *types.AttributeValueMemberN:
+		return SnapshotValue{N: &v.Value}
+	case *types.AttributeValueMemberB:
+		return SnapshotValue{B: v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return SnapshotValue{BOOL: &v.Value}
+	case *types.AttributeValueMemberNULL:
+		return SnapshotValue{NULL: &v.Value}
+	case *types.AttributeValueMemberL:
+		l := make([]SnapshotValue, len(v.Value))
+		for i, e := range v.Value {
+			l[i] = encodeValue(e)
+		}
+		return SnapshotValue{L: l}
+	case *types.AttributeValueMemberM:
+		return SnapshotValue{M: encodeItem(v.Value)}
+	case *types.AttributeValueMemberSS:
+		return SnapshotValue{SS: v.Value}
+	case *types.AttributeValueMemberNS:
+		return SnapshotValue{NS: v.Value}
+	case *types.AttributeValueMemberBS:
+		return SnapshotValue{BS: v.Value}
+	default:
+		return SnapshotValue{}
+	}
+}
+
+func decodeItem(item map[string]SnapshotValue) map[string]types.AttributeValue {
+	out := make(map[string]types.AttributeValue, len(item))
+	for k, v := range item {
+		out[k] = decodeValue(v)
+	}
+	return out
+}
+
+func decodeValue(v SnapshotValue) types.AttributeValue {
+	switch {
+	case v.S != nil:
+		return &types.AttributeValueMemberS{Value: *v.S}
+	case v.N != nil:
+		return &types.AttributeValueMemberN{Value: *v.N}
+	case v.B != nil:
+		return &types.AttributeValueMemberB{Value: v.B}
+	case v.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *v.BOOL}
+	case v.L != nil:
+		l := make([]types.AttributeValue, len(v.L))
+		for i, e := range v.L {
+			l[i] = decodeValue(e)
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	case v.M != nil:
+		return &types.AttributeValueMemberM{Value: decodeItem(v.M)}
+	case v.SS != nil:
+		return &types.AttributeValueMemberSS{Value: v.SS}
+	case v.NS != nil:
+		return &types.AttributeValueMemberNS{Value: v.NS}
+	case v.BS != nil:
+		return &types.AttributeValueMemberBS{Value: v.BS}
+	default:
+		return &types.AttributeValueMemberNULL{Value: aws.ToBool(v.NULL)}
+	}
+}
@@ -0,0 +1,82 @@
+package ddbtest
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	table := "T"
+
+	source := NewMemory()
+	_, err := source.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName:            &table,
+		KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+		AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeS}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 5; n++ {
+		item := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.Itoa(n)}}
+		if _, err := source.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger := &obsv.Logger{Verbosity: obsv.VerbosityQuiet}
+	timeouts := obsv.Timeouts{Control: 5 * time.Second, Data: time.Second}
+	taker := &Snapshotter{Client: source, Stats: obsv.NewStats(), Logger: logger, Timeouts: timeouts}
+	if err := taker.Take(ctx, path, []string{table, "Deleted"}); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	// A fresh backend stands in for the later run, restoring with and
+	// without -control-timeout.
+	for _, control := range []time.Duration{5 * time.Second, 0} {
+		later := NewMemory()
+		restorer := &Snapshotter{Client: later, Stats: obsv.NewStats(), Logger: logger, Timeouts: obsv.Timeouts{Control: control, Data: time.Second}}
+		names, err := restorer.Restore(ctx, path)
+		if err != nil {
+			t.Fatalf("restore with control timeout %s: %v", control, err)
+		}
+		if len(names) != 1 || names[0] != table {
+			t.Errorf("restored tables %v, want [%s]", names, table)
+		}
+		for n := 0; n < 5; n++ {
+			out, err := later.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName: &table,
+				Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.Itoa(n)}},
+			})
+			if err != nil || out.Item == nil {
+				t.Errorf("item %d after restore: %v, %v", n, out.Item, err)
+			}
+		}
+	}
+}
+
+func TestSnapshotValueRoundTrip(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"S":    &types.AttributeValueMemberS{Value: "1"},
+		"N":    &types.AttributeValueMemberN{Value: "1"},
+		"B":    &types.AttributeValueMemberB{Value: []byte{0, 1}},
+		"BOOL": &types.AttributeValueMemberBOOL{Value: false},
+		"NULL": &types.AttributeValueMemberNULL{Value: true},
+		"L":    &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberN{Value: "2"}}},
+		"M":    &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"x": &types.AttributeValueMemberS{Value: "y"}}},
+		"SS":   &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+	}
+	if got := decodeItem(encodeItem(item)); !reflect.DeepEqual(got, item) {
+		t.Errorf("round trip changed the item:\n got %#v\nwant %#v", got, item)
+	}
+}
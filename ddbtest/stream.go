@@ -0,0 +1,79 @@
+package ddbtest
+
+import (
+	"context"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Scanner streams a table's items: one goroutine fetches pages while
+// the caller works through earlier ones, with at most Buffer pages
+// waiting between them. Memory stays bounded by the page size however
+// large the table is.
+type Scanner struct {
+	Client   API
+	Stats    *obsv.Stats
+	Timeouts obsv.Timeouts
+	// PageSize is the Limit of each Scan call; 0 leaves it to the backend.
+	PageSize int32
+	// Buffer is how many fetched pages may wait for the caller.
+	Buffer int
+}
+
+// Scan calls fn for every item of the table name, page by page. An
+// error from fn or from a Scan call stops the scan and is returned.
+func (s *Scanner) Scan(ctx context.Context, name string, fn func(item map[string]types.AttributeValue) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make(chan []map[string]types.AttributeValue, max(s.Buffer, 0))
+	fetched := make(chan error, 1)
+	go func() {
+		defer close(pages)
+		fetched <- s.fetch(ctx, name, pages)
+	}()
+
+	for page := range pages {
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				cancel()
+				for range pages {
+				}
+				return err
+			}
+		}
+	}
+	return <-fetched
+}
+
+// fetch sends each page of the table to pages until the last one.
+func (s *Scanner) fetch(ctx context.Context, name string, pages chan<- []map[string]types.AttributeValue) error {
+	in := &dynamodb.ScanInput{TableName: &name}
+	if s.PageSize > 0 {
+		in.Limit = aws.Int32(s.PageSize)
+	}
+	for ctx.Err() == nil {
+		var page *dynamodb.ScanOutput
+		err := obsv.Timed(ctx, s.Stats, "Scan", s.Timeouts.Data, func(ctx context.Context) error {
+			var err error
+			page, err = s.Client.Scan(ctx, in)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		select {
+		case pages <- page.Items:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if len(page.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		in.ExclusiveStartKey = page.LastEvaluatedKey
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,57 @@
+package ddbtest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestScannerStreamsPages(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	table := "T"
+	if _, err := m.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: &table,
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 50; n++ {
+		item := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.Itoa(n)}}
+		if _, err := m.PutItem(ctx, &dynamodb.PutItemInput{TableName: &table, Item: item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := obsv.NewStats()
+	s := &Scanner{Client: m, Stats: stats, Timeouts: obsv.Timeouts{Data: time.Second}, PageSize: 7, Buffer: 1}
+	seen := 0
+	if err := s.Scan(ctx, table, func(map[string]types.AttributeValue) error { seen++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if pages := stats.TakeWindow().Ops["Scan"].Count; seen != 50 || pages != 8 {
+		t.Errorf("scanned %d items in %d pages, want 50 in 8", seen, pages)
+	}
+
+	stop := errors.New("stop")
+	seen = 0
+	err := s.Scan(ctx, table, func(map[string]types.AttributeValue) error {
+		if seen++; seen == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || seen != 10 {
+		t.Errorf("scan stopped by its callback: got %v after %d items, want %v after 10", err, seen, stop)
+	}
+	if pages := stats.TakeWindow().Ops["Scan"].Count; pages > 4 {
+		t.Errorf("%d pages fetched for 10 items with one page buffered, want the fetcher held back", pages)
+	}
+}
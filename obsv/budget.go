@@ -0,0 +1,110 @@
+package obsv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BudgetError is the cause of a context cut short by an iteration's
+// time budget. It names the phase that was running when the budget ran
+// out and the time each phase before it took.
+type BudgetError struct {
+	Total time.Duration
+	b     *budget
+}
+
+// budget is the time left to one iteration, spent by its phases in
+// turn.
+type budget struct {
+	err      *BudgetError
+	deadline time.Time
+
+	mu     sync.Mutex
+	phases []phaseSpan
+}
+
+// phaseSpan is when one phase ran; end is zero while it runs.
+type phaseSpan struct {
+	name       string
+	start, end time.Time
+}
+
+type budgetKey struct{}
+
+// WithBudget returns a context for one iteration that expires after
+// total, with a BudgetError as its cause. A zero total imposes none.
+func WithBudget(parent context.Context, total time.Duration) (context.Context, context.CancelFunc) {
+	if total <= 0 {
+		return context.WithCancel(parent)
+	}
+	b := &budget{deadline: time.Now().Add(total)}
+	b.err = &BudgetError{Total: total, b: b}
+	ctx, cancel := context.WithDeadlineCause(parent, b.deadline, b.err)
+	return context.WithValue(ctx, budgetKey{}, b), cancel
+}
+
+// Phase derives the context of one phase of an iteration, such as
+// creating its table, from the iteration's context. The phase spends
+// what the phases before it left of the budget; cancel ends it. Without
+// a budget the context is returned as it is.
+func Phase(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	b, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok {
+		return ctx, func() {}
+	}
+	b.mu.Lock()
+	b.phases = append(b.phases, phaseSpan{name: name, start: time.Now()})
+	i := len(b.phases) - 1
+	b.mu.Unlock()
+
+	phaseCtx, cancel := context.WithDeadlineCause(ctx, b.deadline, b.err)
+	return phaseCtx, func() {
+		cancel()
+		b.mu.Lock()
+		if b.phases[i].end.IsZero() {
+			b.phases[i].end = time.Now()
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Phase returns the phase that was running when the budget ran out, or
+// "" if none was.
+func (e *BudgetError) Phase() string {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+	for _, p := range e.b.phases {
+		if p.end.IsZero() || !p.end.Before(e.b.deadline) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+func (e *BudgetError) Error() string {
+	phase := e.Phase()
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+	spent := make([]string, 0, len(e.b.phases))
+	for _, p := range e.b.phases {
+		end := p.end
+		if end.IsZero() || end.After(e.b.deadline) {
+			end = e.b.deadline
+		}
+		spent = append(spent, fmt.Sprintf("%s %s", p.name, end.Sub(p.start).Round(time.Millisecond)))
+		if p.name == phase {
+			break
+		}
+	}
+	where := "between phases"
+	if phase != "" {
+		where = "in phase " + phase
+	}
+	if len(spent) == 0 {
+		return fmt.Sprintf("iteration budget of %s ran out %s", e.Total, where)
+	}
+	return fmt.Sprintf("iteration budget of %s ran out %s (%s)", e.Total, where, strings.Join(spent, ", "))
+}
@@ -0,0 +1,63 @@
+package obsv
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBudgetNamesPhase(t *testing.T) {
+	ctx, cancel := WithBudget(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	createCtx, done := Phase(ctx, "create")
+	if err := createCtx.Err(); err != nil {
+		t.Fatal(err)
+	}
+	done()
+	writeCtx, done := Phase(ctx, "write")
+	defer done()
+	err := Timed(writeCtx, NewStats(), "PutItem", time.Second, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the budget's error wrapping the deadline", err)
+	}
+	if budgetErr.Phase() != "write" || ClassOf(err) != ClassUnavailable {
+		t.Errorf("budget ran out in phase %q with class %s, want write and %s", budgetErr.Phase(), ClassOf(err), ClassUnavailable)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "in phase write (create ") {
+		t.Errorf("error %q does not account for the phases", msg)
+	}
+}
+
+func TestBudgetOwnTimeoutIsNotBlamed(t *testing.T) {
+	ctx, cancel := WithBudget(context.Background(), time.Minute)
+	defer cancel()
+	err := Timed(ctx, NewStats(), "GetItem", time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var budgetErr *BudgetError
+	if !errors.Is(err, context.DeadlineExceeded) || errors.As(err, &budgetErr) {
+		t.Errorf("got %v, want the call's own timeout", err)
+	}
+}
+
+func TestNoBudget(t *testing.T) {
+	ctx, cancel := WithBudget(context.Background(), 0)
+	defer cancel()
+	phaseCtx, done := Phase(ctx, "create")
+	defer done()
+	if phaseCtx != ctx {
+		t.Error("Phase derived a context without a budget")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("a zero budget set a deadline")
+	}
+}
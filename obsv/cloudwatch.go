@@ -0,0 +1,173 @@
+package obsv
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// maxMetricsPerPut stays well inside the PutMetricData request limit.
+const maxMetricsPerPut = 500
+
+// MetricsPublisher periodically pushes the harness's own operation and
+// credential metrics to CloudWatch.
+type MetricsPublisher struct {
+	client    *cloudwatch.Client
+	namespace string
+	stats     *Stats
+	logger    *Logger
+	timeouts  Timeouts
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewMetricsPublisher returns a publisher of stats under namespace.
+func NewMetricsPublisher(cfg aws.Config, namespace string, stats *Stats, logger *Logger, timeouts Timeouts) *MetricsPublisher {
+	return &MetricsPublisher{
+		client:    cloudwatch.NewFromConfig(cfg),
+		namespace: namespace,
+		stats:     stats,
+		logger:    logger,
+		timeouts:  timeouts,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start publishes a window of metrics every interval until Stop is called.
+func (p *MetricsPublisher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.publish(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic publishing and flushes what was recorded since
+// the last publish.
+func (p *MetricsPublisher) Stop() {
+	close(p.stop)
+	<-p.done
+
+	// The run context may already be cancelled; the final flush gets its own.
+	ctx, cancel := OpContext(context.Background(), p.timeouts.Control)
+	defer cancel()
+	p.publish(ctx)
+}
+
+// publish sends one window of metrics. Failures are logged rather than
+// returned so they never end the run.
+func (p *MetricsPublisher) publish(ctx context.Context) {
+	data := metricData(p.stats.TakeWindow(), time.Now())
+	for len(data) > 0 {
+		n := min(len(data), maxMetricsPerPut)
+		batch := data[:n]
+		data = data[n:]
+
+		err := Timed(ctx, p.stats, "PutMetricData", p.timeouts.Data, func(ctx context.Context) error {
+			_, err := p.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+				Namespace:  &p.namespace,
+				MetricData: batch,
+			})
+			return err
+		})
+		if err != nil {
+			p.logger.Operationf("Failed to publish metrics to CloudWatch: %v", err)
+			return
+		}
+	}
+}
+
+// metricData converts a stats window into CloudWatch datums timestamped now.
+func metricData(w Window, now time.Time) []types.MetricDatum {
+	names := make([]string, 0, len(w.Ops))
+	for name := range w.Ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var data []types.MetricDatum
+	for _, name := range names {
+		o := w.Ops[name]
+		dims := []types.Dimension{{Name: aws.String("Operation"), Value: aws.String(name)}}
+		data = append(data,
+			types.MetricDatum{
+				MetricName: aws.String("Calls"),
+				Dimensions: dims,
+				Timestamp:  &now,
+				Unit:       types.StandardUnitCount,
+				Value:      aws.Float64(float64(o.Count)),
+			},
+			types.MetricDatum{
+				MetricName: aws.String("Errors"),
+				Dimensions: dims,
+				Timestamp:  &now,
+				Unit:       types.StandardUnitCount,
+				Value:      aws.Float64(float64(o.Errors)),
+			},
+			types.MetricDatum{
+				MetricName: aws.String("Latency"),
+				Dimensions: dims,
+				Timestamp:  &now,
+				Unit:       types.StandardUnitMilliseconds,
+				StatisticValues: &types.StatisticSet{
+					SampleCount: aws.Float64(float64(o.Count)),
+					Sum:         aws.Float64(milliseconds(o.Total)),
+					Minimum:     aws.Float64(milliseconds(o.Min)),
+					Maximum:     aws.Float64(milliseconds(o.Max)),
+				},
+			},
+		)
+	}
+
+	data = append(data,
+		types.MetricDatum{
+			MetricName: aws.String("CredentialRefreshes"),
+			Timestamp:  &now,
+			Unit:       types.StandardUnitCount,
+			Value:      aws.Float64(float64(w.CredentialRefreshes)),
+		},
+		types.MetricDatum{
+			MetricName: aws.String("CredentialFailures"),
+			Timestamp:  &now,
+			Unit:       types.StandardUnitCount,
+			Value:      aws.Float64(float64(w.CredentialFailures)),
+		},
+	)
+	if w.RedactedSecrets > 0 {
+		data = append(data, types.MetricDatum{
+			MetricName: aws.String("RedactedSecrets"),
+			Timestamp:  &now,
+			Unit:       types.StandardUnitCount,
+			Value:      aws.Float64(float64(w.RedactedSecrets)),
+		})
+	}
+	if !w.CredentialExpires.IsZero() {
+		data = append(data, types.MetricDatum{
+			MetricName: aws.String("CredentialTTL"),
+			Timestamp:  &now,
+			Unit:       types.StandardUnitSeconds,
+			Value:      aws.Float64(w.CredentialExpires.Sub(now).Seconds()),
+		})
+	}
+	return data
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
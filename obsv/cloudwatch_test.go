@@ -0,0 +1,63 @@
+package obsv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestMetricData(t *testing.T) {
+	now := time.Now()
+	ops := map[string]OpSummary{
+		"PutItem": {Count: 4, Errors: 1, Total: 10 * time.Millisecond, Min: time.Millisecond, Max: 4 * time.Millisecond},
+		"GetItem": {Count: 2, Total: 2 * time.Millisecond, Min: time.Millisecond, Max: time.Millisecond},
+	}
+	for _, tc := range []struct {
+		name   string
+		window Window
+		want   []string
+	}{
+		{"empty", Window{}, []string{"CredentialRefreshes", "CredentialFailures"}},
+		{"ops", Window{Ops: ops}, []string{
+			"GetItem/Calls", "GetItem/Errors", "GetItem/Latency",
+			"PutItem/Calls", "PutItem/Errors", "PutItem/Latency",
+			"CredentialRefreshes", "CredentialFailures",
+		}},
+		{"redacted and expiring", Window{RedactedSecrets: 1, CredentialExpires: now.Add(time.Minute)}, []string{
+			"CredentialRefreshes", "CredentialFailures", "RedactedSecrets", "CredentialTTL",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := metricData(tc.window, now)
+			var got []string
+			for _, d := range data {
+				name := aws.ToString(d.MetricName)
+				if len(d.Dimensions) > 0 {
+					name = aws.ToString(d.Dimensions[0].Value) + "/" + name
+				}
+				got = append(got, name)
+				if !d.Timestamp.Equal(now) {
+					t.Errorf("%s timestamped %v, want %v", name, d.Timestamp, now)
+				}
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("metrics %q, want %q", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("metric %d is %s, want %s", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+
+	data := metricData(Window{Ops: ops, CredentialExpires: now.Add(time.Minute)}, now)
+	if latency := data[5].StatisticValues; aws.ToFloat64(latency.SampleCount) != 4 || aws.ToFloat64(latency.Sum) != 10 ||
+		aws.ToFloat64(latency.Minimum) != 1 || aws.ToFloat64(latency.Maximum) != 4 {
+		t.Errorf("PutItem latency %+v, want 4 samples summing to 10ms between 1ms and 4ms", latency)
+	}
+	if ttl := aws.ToFloat64(data[len(data)-1].Value); ttl != 60 {
+		t.Errorf("CredentialTTL %v, want 60s", ttl)
+	}
+}
@@ -0,0 +1,213 @@
+package obsv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// PutLogEvents batch limits: 10,000 events or 1 MiB, where every event
+// costs 26 bytes on top of its message.
+const (
+	maxLogBatchEvents = 10000
+	maxLogBatchBytes  = 1 << 20
+	logEventOverhead  = 26
+)
+
+// CloudWatchLogsSink batches the structured event stream into a
+// CloudWatch Logs stream, flushing on size, on a timer and on Close.
+type CloudWatchLogsSink struct {
+	client  *cloudwatchlogs.Client
+	group   string
+	stream  string
+	stats   *Stats
+	timeout time.Duration
+
+	mu            sync.Mutex
+	pending       []types.InputLogEvent
+	pendingBytes  int
+	sequenceToken *string
+
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewCloudWatchLogsSink creates the log group and stream if needed and
+// ships events to them at least every flush interval. The client's wire
+// logs are off, and the harness leaves CloudWatch Logs out of its signer
+// log, so shipping logs never generates more.
+func NewCloudWatchLogsSink(ctx context.Context, cfg aws.Config, group, stream string, flush time.Duration,
+	stats *Stats, timeouts Timeouts) (*CloudWatchLogsSink, error) {
+	s := &CloudWatchLogsSink{
+		client: cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+			o.ClientLogMode = 0
+		}),
+		group:   group,
+		stream:  stream,
+		stats:   stats,
+		timeout: timeouts.Data,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	var exists *types.ResourceAlreadyExistsException
+	err := Timed(ctx, stats, "CreateLogGroup", timeouts.Control, func(ctx context.Context) error {
+		_, err := s.client.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{LogGroupName: &s.group})
+		return err
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return nil, err
+	}
+	err = Timed(ctx, stats, "CreateLogStream", timeouts.Control, func(ctx context.Context) error {
+		_, err := s.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+			LogGroupName:  &s.group,
+			LogStreamName: &s.stream,
+		})
+		return err
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return nil, err
+	}
+
+	go s.flushLoop(flush)
+	return s, nil
+}
+
+// Write queues e, flushing straight away if the batch is full.
+func (s *CloudWatchLogsSink) Write(e Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, types.InputLogEvent{
+		Message:   aws.String(string(data)),
+		Timestamp: aws.Int64(e.Time.UnixMilli()),
+	})
+	s.pendingBytes += len(data) + logEventOverhead
+	full := len(s.pending) >= maxLogBatchEvents || s.pendingBytes >= maxLogBatchBytes*9/10
+	s.mu.Unlock()
+
+	if full {
+		go func() {
+			ctx, cancel := OpContext(context.Background(), s.timeout)
+			defer cancel()
+			s.flush(ctx)
+		}()
+	}
+}
+
+func (s *CloudWatchLogsSink) flushLoop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := OpContext(context.Background(), s.timeout)
+			s.flush(ctx)
+			cancel()
+		}
+	}
+}
+
+// Close stops the timer and ships whatever is still pending.
+func (s *CloudWatchLogsSink) Close() {
+	close(s.stop)
+	<-s.done
+
+	ctx, cancel := OpContext(context.Background(), s.timeout)
+	defer cancel()
+	s.flush(ctx)
+}
+
+// flush sends the pending events in order. Errors go to the standard
+// logger only: reporting them through the harness logger would feed
+// them straight back into this sink.
+func (s *CloudWatchLogsSink) flush(ctx context.Context) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	events := s.pending
+	s.pending = nil
+	s.pendingBytes = 0
+	s.mu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+	// A batch must be in chronological order.
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].Timestamp < *events[j].Timestamp
+	})
+
+	for len(events) > 0 {
+		n := logBatchLen(events)
+		if err := s.put(ctx, events[:n]); err != nil {
+			log.Printf("[CLOUDWATCH LOGS] dropped %d events: %v", len(events), err)
+			return
+		}
+		events = events[n:]
+	}
+}
+
+// logBatchLen returns how many of events fit in one PutLogEvents call.
+// An oversized event still goes on its own so a flush always advances.
+func logBatchLen(events []types.InputLogEvent) int {
+	n, size := 0, 0
+	for n < len(events) && n < maxLogBatchEvents {
+		size += len(*events[n].Message) + logEventOverhead
+		if size > maxLogBatchBytes {
+			break
+		}
+		n++
+	}
+	return max(n, 1)
+}
+
+// put sends one batch, retrying once with the expected sequence token if
+// the service rejects the one we hold.
+func (s *CloudWatchLogsSink) put(ctx context.Context, batch []types.InputLogEvent) error {
+	for attempt := 0; ; attempt++ {
+		var out *cloudwatchlogs.PutLogEventsOutput
+		err := Timed(ctx, s.stats, "PutLogEvents", 0, func(ctx context.Context) error {
+			var err error
+			out, err = s.client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+				LogGroupName:  &s.group,
+				LogStreamName: &s.stream,
+				LogEvents:     batch,
+				SequenceToken: s.sequenceToken,
+			})
+			return err
+		})
+
+		var invalid *types.InvalidSequenceTokenException
+		var accepted *types.DataAlreadyAcceptedException
+		switch {
+		case err == nil:
+			s.sequenceToken = out.NextSequenceToken
+			return nil
+		case errors.As(err, &accepted):
+			s.sequenceToken = accepted.ExpectedSequenceToken
+			return nil
+		case errors.As(err, &invalid) && attempt == 0:
+			s.sequenceToken = invalid.ExpectedSequenceToken
+		default:
+			return err
+		}
+	}
+}
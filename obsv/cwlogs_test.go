@@ -0,0 +1,34 @@
+package obsv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+func TestLogBatchLen(t *testing.T) {
+	events := func(n, size int) []types.InputLogEvent {
+		out := make([]types.InputLogEvent, n)
+		for i := range out {
+			out[i].Message = aws.String(strings.Repeat("x", size))
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name   string
+		events []types.InputLogEvent
+		want   int
+	}{
+		{"few", events(3, 100), 3},
+		{"event limit", events(maxLogBatchEvents+5, 1), maxLogBatchEvents},
+		{"byte limit", events(4, maxLogBatchBytes/3), 2},
+		{"exactly full", events(2, maxLogBatchBytes/2-logEventOverhead), 2},
+		{"oversized", events(2, maxLogBatchBytes+1), 1},
+	} {
+		if got := logBatchLen(tc.events); got != tc.want {
+			t.Errorf("%s: logBatchLen = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package obsv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDraining is the cause of an operation refused because the run is
+// shutting down.
+var ErrDraining = errors.New("not started: the run is shutting down")
+
+// Drain lets a run shut down without abandoning calls mid-flight. Once
+// started it refuses new calls made under its contexts, gives those in
+// flight until a timeout to finish and only then cancels them. A nil
+// Drain never drains.
+type Drain struct {
+	mu       sync.Mutex
+	draining bool
+	inFlight int
+	stopping chan struct{}
+	abort    []context.CancelFunc
+}
+
+type drainKey struct{}
+
+// NewDrain returns a Drain that has not started.
+func NewDrain() *Drain {
+	return &Drain{stopping: make(chan struct{})}
+}
+
+// Bind returns a context under which calls are admitted by d and which
+// is cancelled if d's drain times out.
+func (d *Drain) Bind(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if d == nil {
+		return ctx, cancel
+	}
+	d.mu.Lock()
+	d.abort = append(d.abort, cancel)
+	d.mu.Unlock()
+	return context.WithValue(ctx, drainKey{}, d), cancel
+}
+
+// WithoutDrain returns ctx with calls under it admitted even while
+// draining, for work that must finish once begun, such as writing a
+// batch already buffered.
+func WithoutDrain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, drainKey{}, (*Drain)(nil))
+}
+
+// Draining reports whether calls under ctx are refused.
+func Draining(ctx context.Context) bool {
+	d, _ := ctx.Value(drainKey{}).(*Drain)
+	return d.Draining()
+}
+
+// Start refuses new calls and cancels those still in flight after
+// timeout. It returns how many were in flight.
+func (d *Drain) Start(timeout time.Duration) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.draining {
+		d.draining = true
+		close(d.stopping)
+		time.AfterFunc(timeout, d.Abort)
+	}
+	return d.inFlight
+}
+
+// Abort cancels the calls still in flight.
+func (d *Drain) Abort() {
+	d.mu.Lock()
+	abort := d.abort
+	d.mu.Unlock()
+	for _, cancel := range abort {
+		cancel()
+	}
+}
+
+// Draining reports whether d has started.
+func (d *Drain) Draining() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Stopping is closed once d starts; it is nil, and never closes, for a
+// nil Drain.
+func (d *Drain) Stopping() <-chan struct{} {
+	if d == nil {
+		return nil
+	}
+	return d.stopping
+}
+
+// admit counts a call starting under ctx, unless ctx's drain has
+// started, and returns the func that counts it finished.
+func admit(ctx context.Context) (func(), bool) {
+	d, _ := ctx.Value(drainKey{}).(*Drain)
+	if d == nil {
+		return func() {}, true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, false
+	}
+	d.inFlight++
+	return func() {
+		d.mu.Lock()
+		d.inFlight--
+		d.mu.Unlock()
+	}, true
+}
@@ -0,0 +1,68 @@
+package obsv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrainRefusesNewCalls(t *testing.T) {
+	d := NewDrain()
+	ctx, cancel := d.Bind(context.Background())
+	defer cancel()
+	stats := NewStats()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	inFlight := make(chan error)
+	go func() {
+		inFlight <- Timed(ctx, stats, "PutItem", 0, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return ctx.Err()
+		})
+	}()
+	<-started
+	if n := d.Start(time.Hour); n != 1 {
+		t.Errorf("Start found %d calls in flight, want 1", n)
+	}
+	select {
+	case <-d.Stopping():
+	default:
+		t.Error("Stopping is not closed once draining")
+	}
+
+	err := Timed(ctx, stats, "PutItem", 0, func(context.Context) error { return nil })
+	var he *HarnessError
+	if !errors.Is(err, ErrDraining) || !errors.As(err, &he) || he.Class != ClassUnavailable {
+		t.Errorf("call while draining: got %v, want it refused", err)
+	}
+	if err := Timed(WithoutDrain(ctx), stats, "BatchWriteItem", 0, func(context.Context) error { return nil }); err != nil {
+		t.Errorf("call exempt from draining: %v", err)
+	}
+	close(release)
+	if err := <-inFlight; err != nil {
+		t.Errorf("call in flight when draining started: %v, want it to finish", err)
+	}
+}
+
+func TestDrainTimeoutCancels(t *testing.T) {
+	d := NewDrain()
+	ctx, cancel := d.Bind(context.Background())
+	defer cancel()
+	d.Start(10 * time.Millisecond)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("calls in flight were not cancelled once the drain timed out")
+	}
+}
+
+func TestNilDrain(t *testing.T) {
+	var d *Drain
+	ctx, cancel := d.Bind(context.Background())
+	defer cancel()
+	if d.Draining() || Draining(ctx) || d.Stopping() != nil {
+		t.Error("a nil Drain drains")
+	}
+}
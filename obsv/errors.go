@@ -0,0 +1,211 @@
+package obsv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// ErrorClass groups failures by cause so wrapping scripts can tell them apart.
+type ErrorClass string
+
+const (
+	ClassUnknown     ErrorClass = "unknown"
+	ClassConfig      ErrorClass = "config"
+	ClassCredentials ErrorClass = "credentials"
+	ClassThrottling  ErrorClass = "throttling"
+	ClassUnavailable ErrorClass = "unavailable"
+	ClassMismatch    ErrorClass = "mismatch"
+)
+
+// ExitCode returns the process exit code reported for the class.
+func (c ErrorClass) ExitCode() int {
+	switch c {
+	case ClassConfig:
+		return 2
+	case ClassCredentials:
+		return 3
+	case ClassThrottling:
+		return 4
+	case ClassUnavailable:
+		return 5
+	case ClassMismatch:
+		return 6
+	default:
+		return 1
+	}
+}
+
+// HarnessError is a failure tagged with the operation and its class.
+type HarnessError struct {
+	Class ErrorClass
+	Op    string
+	Err   error
+}
+
+func (e *HarnessError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *HarnessError) Unwrap() error {
+	return e.Err
+}
+
+// ClockSkewError is a signing failure diagnosed against the server's
+// clock, as read from the Date header of the response that rejected it.
+type ClockSkewError struct {
+	// Skew is how far the server's clock runs ahead of the local one.
+	Skew time.Duration
+	Err  error
+}
+
+// maxClockSkew is how far apart the clocks may be before SigV4 requests
+// are rejected as skewed or expired.
+const maxClockSkew = 5 * time.Minute
+
+func (e *ClockSkewError) Error() string {
+	skew := e.Skew.Round(time.Second)
+	if skew.Abs() < time.Minute {
+		return fmt.Sprintf("%v (local and server clocks agree to within %s, so this is not clock skew; "+
+			"check the credentials, -region and -signing-version)", e.Err, max(skew.Abs(), time.Second))
+	}
+	direction := "behind"
+	if skew < 0 {
+		direction = "ahead of"
+	}
+	return fmt.Sprintf("%v (the local clock is %s %s the server's and requests are rejected past %s; "+
+		"sync it with NTP, for example \"timedatectl set-ntp true\", or restart the VM or container "+
+		"whose clock drifted, such as with \"wsl --shutdown\" or by restarting Docker Desktop)",
+		e.Err, skew.Abs(), direction, maxClockSkew)
+}
+
+func (e *ClockSkewError) Unwrap() error {
+	return e.Err
+}
+
+var throttlingCodes = map[string]bool{
+	"ThrottlingException":                    true,
+	"Throttling":                             true,
+	"ThrottledException":                     true,
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"LimitExceededException":                 true,
+	"RequestThrottled":                       true,
+	"TooManyRequestsException":               true,
+	"SlowDown":                               true,
+}
+
+var credentialCodes = map[string]bool{
+	"UnrecognizedClientException": true,
+	"InvalidClientTokenId":        true,
+	"InvalidAccessKeyId":          true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidSignatureException":   true,
+	"SignatureDoesNotMatch":       true,
+	"RequestTimeTooSkewed":        true,
+	"RequestExpired":              true,
+	"MissingAuthenticationToken":  true,
+	"AuthFailure":                 true,
+}
+
+var unavailableCodes = map[string]bool{
+	"ServiceUnavailable":          true,
+	"ServiceUnavailableException": true,
+	"InternalFailure":             true,
+	"InternalServerError":         true,
+	"InternalError":               true,
+}
+
+// Classify wraps err as a HarnessError for op, inferring the class from
+// the SDK error when the caller has not already tagged it.
+func Classify(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var he *HarnessError
+	if errors.As(err, &he) {
+		if he.Op == op {
+			return he
+		}
+		return &HarnessError{Class: he.Class, Op: op, Err: err}
+	}
+	return &HarnessError{Class: ClassOf(err), Op: op, Err: err}
+}
+
+// ClassOf inspects an untagged error for a known cause.
+func ClassOf(err error) ErrorClass {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		switch {
+		case throttlingCodes[code]:
+			return ClassThrottling
+		case credentialCodes[code]:
+			return ClassCredentials
+		case unavailableCodes[code]:
+			return ClassUnavailable
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch status := respErr.HTTPStatusCode(); {
+		case status == 429:
+			return ClassThrottling
+		case status >= 500:
+			return ClassUnavailable
+		}
+	}
+
+	var sendErr *smithyhttp.RequestSendError
+	var netErr net.Error
+	if errors.As(err, &sendErr) || errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return ClassUnavailable
+	}
+
+	return ClassUnknown
+}
+
+// errorSummary is the machine-readable line written when the run fails.
+type errorSummary struct {
+	Status    string     `json:"status"`
+	Class     ErrorClass `json:"class"`
+	ExitCode  int        `json:"exit_code"`
+	Operation string     `json:"operation,omitempty"`
+	Error     string     `json:"error"`
+}
+
+// ReportError writes the error summary for err, with its secrets
+// redacted, to w and returns the exit code to terminate with. A nil
+// error reports nothing and returns 0.
+func ReportError(w io.Writer, err error) int {
+	if err == nil {
+		return 0
+	}
+
+	he, ok := err.(*HarnessError)
+	if !ok {
+		he = &HarnessError{Class: ClassOf(err), Err: err}
+	}
+
+	msg, _ := Redact(he.Err.Error())
+	summary := errorSummary{
+		Status:    "error",
+		Class:     he.Class,
+		ExitCode:  he.Class.ExitCode(),
+		Operation: he.Op,
+		Error:     msg,
+	}
+	_ = json.NewEncoder(w).Encode(summary)
+	return summary.ExitCode
+}
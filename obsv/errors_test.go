@@ -0,0 +1,63 @@
+package obsv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// statusError is a response error with no recognised code.
+func statusError(status int) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      errors.New("failed"),
+	}}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want ErrorClass
+		exit int
+	}{
+		{"throttled", &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"}, ClassThrottling, 4},
+		{"bad credentials", &smithy.GenericAPIError{Code: "UnrecognizedClientException"}, ClassCredentials, 3},
+		{"service down", &smithy.GenericAPIError{Code: "ServiceUnavailable"}, ClassUnavailable, 5},
+		{"429", statusError(429), ClassThrottling, 4},
+		{"503", statusError(503), ClassUnavailable, 5},
+		{"400", statusError(400), ClassUnknown, 1},
+		{"send failed", &smithyhttp.RequestSendError{Err: errors.New("connection refused")}, ClassUnavailable, 5},
+		{"timed out", fmt.Errorf("GetItem: %w", context.DeadlineExceeded), ClassUnavailable, 5},
+		{"tagged", &HarnessError{Class: ClassMismatch, Op: "GetItem", Err: errors.New("wrong item")}, ClassMismatch, 6},
+		{"unknown", errors.New("something else"), ClassUnknown, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var he *HarnessError
+			if err := Classify("Op", tc.err); !errors.As(err, &he) || he.Class != tc.want || he.Op != "Op" {
+				t.Fatalf("Classify = %#v, want class %s for Op", err, tc.want)
+			}
+
+			var buf bytes.Buffer
+			if code := ReportError(&buf, Classify("Op", tc.err)); code != tc.exit {
+				t.Errorf("exit code %d, want %d", code, tc.exit)
+			}
+			var summary errorSummary
+			if err := json.Unmarshal(buf.Bytes(), &summary); err != nil || summary.Class != tc.want || summary.Operation != "Op" {
+				t.Errorf("summary %s (%v), want class %s for Op", buf.Bytes(), err, tc.want)
+			}
+		})
+	}
+
+	if code := ReportError(&bytes.Buffer{}, nil); code != 0 {
+		t.Errorf("nil error exits %d, want 0", code)
+	}
+}
@@ -0,0 +1,217 @@
+package obsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions accepted by -events-compress.
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// partialSuffix marks an event file still being written. Close renames
+// it away, so a file without it was finalized.
+const partialSuffix = ".partial"
+
+// FileSink writes the structured event stream to a JSON Lines file,
+// optionally compressed. The file is written under its name plus
+// ".partial" and only renamed into place by Close; until then it is
+// flushed at least every flush interval, so a run that dies still leaves
+// a readable prefix of its events.
+type FileSink struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	zw   io.WriteCloser
+	enc  *json.Encoder
+	err  error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// flusher is implemented by the compressors, which hold data back until
+// told to emit a sync point.
+type flusher interface {
+	Flush() error
+}
+
+// NewFileSink creates the event file path, compressed as compression.
+func NewFileSink(path, compression string, flush time.Duration) (*FileSink, error) {
+	file, err := os.Create(path + partialSuffix)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSink{
+		path: path,
+		file: file,
+		buf:  bufio.NewWriter(file),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	var w io.Writer = s.buf
+	switch compression {
+	case CompressNone:
+	case CompressGzip:
+		s.zw = gzip.NewWriter(s.buf)
+	case CompressZstd:
+		s.zw, err = zstd.NewWriter(s.buf)
+	default:
+		err = fmt.Errorf("unknown compression %q", compression)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	if s.zw != nil {
+		w = s.zw
+	}
+	s.enc = json.NewEncoder(w)
+
+	go s.flushLoop(flush)
+	return s, nil
+}
+
+// Write appends e as one line. The first error stops writing and is
+// returned by Close.
+func (s *FileSink) Write(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil || s.enc == nil {
+		return
+	}
+	if err := s.enc.Encode(e); err != nil {
+		s.fail(err)
+	}
+}
+
+func (s *FileSink) flushLoop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flush()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// flush pushes buffered events through to the file. It reports errors
+// to the standard logger only, like the CloudWatch Logs sink, since the harness logger
+// would route them straight back here.
+func (s *FileSink) flush() {
+	if s.err != nil || s.enc == nil {
+		return
+	}
+	if f, ok := s.zw.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	if err := s.buf.Flush(); err != nil {
+		s.fail(err)
+	}
+}
+
+func (s *FileSink) fail(err error) {
+	s.err = err
+	log.Printf("Writing events to %s failed, no more will be written: %v", s.file.Name(), err)
+}
+
+// Close finishes the stream, syncs it to disk and renames it into
+// place. A file that could not be written completely keeps its
+// ".partial" name.
+func (s *FileSink) Close() error {
+	close(s.stop)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.zw != nil && s.err == nil {
+		s.err = s.zw.Close()
+	}
+	if s.err == nil {
+		s.err = s.buf.Flush()
+	}
+	if s.err == nil {
+		s.err = s.file.Sync()
+	}
+	s.enc = nil
+	if err := s.file.Close(); s.err == nil {
+		s.err = err
+	}
+	if s.err != nil {
+		return fmt.Errorf("events left unfinished in %s: %w", s.file.Name(), s.err)
+	}
+	return os.Rename(s.file.Name(), s.path)
+}
+
+// Magic numbers that open gzip and zstd streams.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ReadEvents calls fn for each event in an event file, whichever way it
+// was compressed. A file cut short by a run that died yields the events
+// up to its last flush and then the error that cut it short.
+func ReadEvents(r io.Reader, fn func(Event) error) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+	var in io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		in = zr
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		in = zr
+	}
+
+	dec := json.NewDecoder(in)
+	for {
+		var e Event
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package obsv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeEvents(s *FileSink, n int) []Event {
+	events := make([]Event, n)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := range events {
+		events[i] = Event{Time: start.Add(time.Duration(i) * time.Second), Category: CategoryOperation, Message: fmt.Sprintf("event %d", i)}
+		s.Write(events[i])
+	}
+	return events
+}
+
+func readEventFile(t *testing.T, path string) ([]Event, error) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Event
+	err = ReadEvents(f, func(e Event) error {
+		got = append(got, e)
+		return nil
+	})
+	return got, err
+}
+
+func TestFileSinkRoundTrip(t *testing.T) {
+	for _, compression := range []string{CompressNone, CompressGzip, CompressZstd} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.jsonl")
+			s, err := NewFileSink(path, compression, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := writeEvents(s, 500)
+			if err := s.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+			if _, err := os.Stat(path + partialSuffix); !os.IsNotExist(err) {
+				t.Errorf("partial file left after close: %v", err)
+			}
+
+			got, err := readEventFile(t, path)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("read %d events, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !got[i].Time.Equal(want[i].Time) || got[i].Message != want[i].Message {
+					t.Fatalf("event %d is %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// A run that dies before Close leaves its flushed events readable.
+func TestFileSinkFlushedPrefix(t *testing.T) {
+	for _, compression := range []string{CompressGzip, CompressZstd} {
+		t.Run(compression, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.jsonl")
+			s, err := NewFileSink(path, compression, 10*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			writeEvents(s, 20)
+			time.Sleep(100 * time.Millisecond)
+
+			got, err := readEventFile(t, path+partialSuffix)
+			if len(got) != 20 {
+				t.Errorf("read %d events from the unfinished file, want 20", len(got))
+			}
+			if err == nil {
+				t.Error("read the unfinished file without error, want it reported as cut short")
+			}
+		})
+	}
+}
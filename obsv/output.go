@@ -0,0 +1,126 @@
+// Package obsv is the harness's observability: the categorised event
+// logger and its sinks, per-operation stats, error classification, and
+// publishing metrics and logs to CloudWatch.
+package obsv
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go/logging"
+)
+
+// Verbosity selects which categories of output are written.
+type Verbosity int
+
+const (
+	// VerbosityQuiet shows credential events only.
+	VerbosityQuiet Verbosity = iota
+	// VerbosityNormal adds table and item operations.
+	VerbosityNormal
+	// VerbosityVerbose adds SDK request, response and retry logs.
+	VerbosityVerbose
+	// VerbosityDebug adds full SDK wire logs including bodies.
+	VerbosityDebug
+)
+
+// Event categories, as they appear in the structured event stream.
+const (
+	CategoryCredentials = "credentials"
+	CategoryOperation   = "operation"
+	CategorySDK         = "sdk"
+)
+
+// Event is one line of harness output in structured form.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Category string    `json:"category"`
+	Message  string    `json:"message"`
+}
+
+// Sink receives every event that passes the verbosity gate.
+type Sink interface {
+	Write(e Event)
+}
+
+// Logger routes harness output by category, dropping anything
+// above the configured verbosity. Every line it writes has secrets
+// redacted first.
+type Logger struct {
+	Verbosity Verbosity
+	// Stats, if set, counts the secrets redacted.
+	Stats *Stats
+
+	mu    sync.Mutex
+	sinks []Sink
+}
+
+// AddSink sends all subsequent events to s as well as the console.
+func (l *Logger) AddSink(s Sink) {
+	l.mu.Lock()
+	l.sinks = append(l.sinks, s)
+	l.mu.Unlock()
+}
+
+// redact strips secrets from msg, warning through Stats if it found any.
+func (l *Logger) redact(msg string) string {
+	msg, n := Redact(msg)
+	if n > 0 && l.Stats != nil {
+		l.Stats.SecretsRedacted(n)
+	}
+	return msg
+}
+
+func (l *Logger) emit(category, msg string) {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.mu.Unlock()
+
+	e := Event{Time: time.Now(), Category: category, Message: msg}
+	for _, s := range sinks {
+		s.Write(e)
+	}
+}
+
+// Credentialf logs a credential event. These are shown at every verbosity.
+func (l *Logger) Credentialf(format string, v ...interface{}) {
+	msg := l.redact(fmt.Sprintf(format, v...))
+	log.Printf("[CREDENTIALS] %s", msg)
+	l.emit(CategoryCredentials, msg)
+}
+
+// Operationf prints the result of a table or item operation.
+func (l *Logger) Operationf(format string, v ...interface{}) {
+	if l.Verbosity < VerbosityNormal {
+		return
+	}
+	msg := l.redact(fmt.Sprintf(format, v...))
+	fmt.Println(msg)
+	l.emit(CategoryOperation, msg)
+}
+
+// Logf implements logging.Logger so the SDK's wire logs share the
+// same verbosity gate.
+func (l *Logger) Logf(classification logging.Classification, format string, v ...interface{}) {
+	if l.Verbosity < VerbosityVerbose {
+		return
+	}
+	msg := l.redact(fmt.Sprintf(format, v...))
+	log.Printf("[SDK %s] %s", classification, msg)
+	l.emit(CategorySDK, msg)
+}
+
+// ClientLogMode returns the SDK log mode matching the verbosity.
+func (l *Logger) ClientLogMode() aws.ClientLogMode {
+	switch {
+	case l.Verbosity >= VerbosityDebug:
+		return aws.LogRetries | aws.LogRequestWithBody | aws.LogResponseWithBody
+	case l.Verbosity >= VerbosityVerbose:
+		return aws.LogRetries | aws.LogRequest | aws.LogResponse
+	default:
+		return 0
+	}
+}
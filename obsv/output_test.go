@@ -0,0 +1,40 @@
+package obsv
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go/logging"
+)
+
+func TestLoggerVerbosity(t *testing.T) {
+	for _, tc := range []struct {
+		verbosity Verbosity
+		want      []string
+		logMode   aws.ClientLogMode
+	}{
+		{VerbosityQuiet, []string{CategoryCredentials}, 0},
+		{VerbosityNormal, []string{CategoryCredentials, CategoryOperation}, 0},
+		{VerbosityVerbose, []string{CategoryCredentials, CategoryOperation, CategorySDK}, aws.LogRetries | aws.LogRequest | aws.LogResponse},
+		{VerbosityDebug, []string{CategoryCredentials, CategoryOperation, CategorySDK}, aws.LogRetries | aws.LogRequestWithBody | aws.LogResponseWithBody},
+	} {
+		var events eventRecorder
+		l := &Logger{Verbosity: tc.verbosity}
+		l.AddSink(&events)
+		l.Credentialf("credential")
+		l.Operationf("operation")
+		l.Logf(logging.Debug, "sdk")
+
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Category)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("verbosity %d wrote %v, want %v", tc.verbosity, got, tc.want)
+		}
+		if mode := l.ClientLogMode(); mode != tc.logMode {
+			t.Errorf("verbosity %d has client log mode %d, want %d", tc.verbosity, mode, tc.logMode)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package obsv
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// RedactionMarker replaces each secret the logger finds in its output.
+const RedactionMarker = "[REDACTED]"
+
+var (
+	// namedSecret matches a value given under a secret's name, as in
+	// AWS_SECRET_ACCESS_KEY=..., "SessionToken":"...",
+	// <SecretAccessKey>...</SecretAccessKey> or an X-Amz-Security-Token
+	// header.
+	namedSecret = regexp.MustCompile(`(?i)((?:secret[_-]?access[_-]?key|session[_-]?token|security[_-]?token)["']?\s*[:=>]\s*["']?)([A-Za-z0-9/+=]{16,})`)
+	// sessionToken matches STS session tokens by the prefixes they are
+	// issued with.
+	sessionToken = regexp.MustCompile(`(?:FQoGZXIvYXdz|FwoGZXIvYXdz|IQoJb3JpZ2lu)[A-Za-z0-9/+=]+`)
+	// secretKeyRun matches a run of the characters a secret access key is
+	// written with, URL-encoded or not; keys are found within it by
+	// redactKeys.
+	secretKeyRun = regexp.MustCompile(`(?:[A-Za-z0-9/+=]|%2[BbFf]|%3[Dd])+`)
+)
+
+// secretKeyLen is the length of a secret access key.
+const secretKeyLen = 40
+
+// Redact replaces the AWS secret access keys and session tokens in s
+// with RedactionMarker and returns how many it replaced. Bare 40
+// character runs only count as keys if they mix upper and lower case
+// letters, which keeps hex digests such as SHA-1 sums readable, and if
+// they are the whole run or are cut from the rest of it by a slash or
+// equals sign, as in key=... or a path.
+func Redact(s string) (string, int) {
+	n := 0
+	s = namedSecret.ReplaceAllStringFunc(s, func(m string) string {
+		n++
+		sub := namedSecret.FindStringSubmatch(m)
+		return sub[1] + RedactionMarker
+	})
+	s = sessionToken.ReplaceAllStringFunc(s, func(string) string {
+		n++
+		return RedactionMarker
+	})
+	s = secretKeyRun.ReplaceAllStringFunc(s, func(m string) string {
+		m, keys := redactKeys(m)
+		n += keys
+		return m
+	})
+	return s, n
+}
+
+// redactKeys replaces the secret access keys in a run matched by
+// secretKeyRun, counting each URL escape as the character it encodes.
+func redactKeys(run string) (string, int) {
+	var chars []byte
+	var offsets []int
+	for i := 0; i < len(run); {
+		offsets = append(offsets, i)
+		if run[i] != '%' {
+			chars = append(chars, run[i])
+			i++
+			continue
+		}
+		switch strings.ToUpper(run[i+1 : i+3]) {
+		case "2B":
+			chars = append(chars, '+')
+		case "2F":
+			chars = append(chars, '/')
+		default:
+			chars = append(chars, '=')
+		}
+		i += 3
+	}
+	offsets = append(offsets, len(run))
+
+	separator := func(i int) bool { return chars[i] == '/' || chars[i] == '=' }
+	var b strings.Builder
+	n, last := 0, 0
+	for start := 0; start+secretKeyLen <= len(chars); start++ {
+		end := start + secretKeyLen
+		if start > 0 && !separator(start-1) || end < len(chars) && !separator(end) || !isSecretKey(chars[start:end]) {
+			continue
+		}
+		b.WriteString(run[offsets[last]:offsets[start]])
+		b.WriteString(RedactionMarker)
+		n++
+		last, start = end, end
+	}
+	if n == 0 {
+		return run, 0
+	}
+	b.WriteString(run[offsets[last]:])
+	return b.String(), n
+}
+
+// isSecretKey reports whether key has the shape of a secret access key:
+// no padding, and both upper and lower case letters.
+func isSecretKey(key []byte) bool {
+	k := string(key)
+	return !strings.Contains(k, "=") &&
+		strings.ContainsAny(k, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+		strings.ContainsAny(k, "abcdefghijklmnopqrstuvwxyz")
+}
+
+// redactingWriter passes writes on with their secrets redacted.
+type redactingWriter struct{ w io.Writer }
+
+// NewRedactingWriter returns a writer that redacts each write before
+// passing it to w. The log package writes each line with one call, so
+// it suits log.SetOutput; it is what keeps log lines the Logger does not
+// compose, such as a failing sink's, free of secrets.
+func NewRedactingWriter(w io.Writer) io.Writer { return redactingWriter{w} }
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	s, _ := Redact(string(p))
+	if _, err := io.WriteString(r.w, s); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
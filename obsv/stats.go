@@ -0,0 +1,413 @@
+package obsv
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Stats accumulates per-operation counts and latencies for the summary
+// report. Per-call figures are spread over shards, each with its own
+// lock, and only added up when read, so concurrent workers recording
+// calls seldom wait for one another.
+type Stats struct {
+	shards []statsShard
+	mask   uint32
+
+	mu         sync.Mutex
+	started    time.Time
+	iterations int
+	creds      credStats
+	// credExpires is the expiry of the last refreshed credentials.
+	credExpires time.Time
+	windowCreds credStats
+	// redacted and windowRedacted count secrets the logger redacted.
+	redacted       int
+	windowRedacted int
+	// failures counts failed iterations by the class of what failed.
+	failures map[ErrorClass]int
+	// corrupt and missing count the damaged items found under -integrity.
+	corrupt int
+	missing int
+}
+
+// statsShard holds the per-call figures recorded on it.
+type statsShard struct {
+	mu  sync.Mutex
+	ops map[string]*opStats
+	// window holds the same figures since the last TakeWindow call.
+	window   map[string]*opStats
+	attempts map[string]*attemptStats // keyed by SDK operation name
+	cache    cacheStats
+	conns    connStats
+	// Padding keeps neighbouring shards' locks off one cache line.
+	_ [64]byte
+}
+
+type opStats struct {
+	count  int
+	errors int
+	total  time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+type attemptStats struct {
+	calls    int
+	attempts int
+	retried  int
+	max      int
+}
+
+type cacheStats struct {
+	hits   int
+	misses int
+}
+
+type connStats struct {
+	new    int
+	reused int
+	// idle is the total time reused connections had sat idle.
+	idle time.Duration
+}
+
+type credStats struct {
+	refreshes int
+	failures  int
+}
+
+// OpSummary is a copy of the figures recorded for one operation.
+type OpSummary struct {
+	Count  int
+	Errors int
+	Total  time.Duration
+	Min    time.Duration
+	Max    time.Duration
+}
+
+// Window is what was recorded between two TakeWindow calls.
+type Window struct {
+	Ops                 map[string]OpSummary
+	CredentialRefreshes int
+	CredentialFailures  int
+	// RedactedSecrets counts secrets redacted from log output, which
+	// should never have been logged.
+	RedactedSecrets int
+	// CredentialExpires is the expiry of the current credentials, zero
+	// if they do not expire or have not been retrieved.
+	CredentialExpires time.Time
+}
+
+// NewStats returns an empty Stats whose elapsed time starts now.
+func NewStats() *Stats {
+	// A power of two at least GOMAXPROCS, so choosing a shard is a mask.
+	n := 1 << bits.Len(uint(runtime.GOMAXPROCS(0)-1))
+	s := &Stats{
+		shards:  make([]statsShard, n),
+		mask:    uint32(n - 1),
+		started: time.Now(),
+	}
+	for i := range s.shards {
+		s.shards[i].ops = make(map[string]*opStats)
+		s.shards[i].window = make(map[string]*opStats)
+		s.shards[i].attempts = make(map[string]*attemptStats)
+	}
+	return s
+}
+
+// shard returns a shard to record on. Picking at random spreads callers
+// without their having to say which worker they are.
+func (s *Stats) shard() *statsShard {
+	sh := &s.shards[rand.Uint32()&s.mask]
+	sh.mu.Lock()
+	return sh
+}
+
+func (o *opStats) add(d time.Duration, err error) {
+	if o.count == 0 || d < o.min {
+		o.min = d
+	}
+	if d > o.max {
+		o.max = d
+	}
+	o.count++
+	if err != nil {
+		o.errors++
+	}
+	o.total += d
+}
+
+func (o *opStats) merge(other *opStats) {
+	if o.count == 0 || other.min < o.min {
+		o.min = other.min
+	}
+	o.max = max(o.max, other.max)
+	o.count += other.count
+	o.errors += other.errors
+	o.total += other.total
+}
+
+func (a *attemptStats) merge(other *attemptStats) {
+	a.calls += other.calls
+	a.attempts += other.attempts
+	a.retried += other.retried
+	a.max = max(a.max, other.max)
+}
+
+// mergeOps adds the figures in from to those in into.
+func mergeOps(into, from map[string]*opStats) {
+	for name, o := range from {
+		sum, ok := into[name]
+		if !ok {
+			sum = &opStats{}
+			into[name] = sum
+		}
+		sum.merge(o)
+	}
+}
+
+// mergeAttempts adds the figures in from to those in into.
+func mergeAttempts(into, from map[string]*attemptStats) {
+	for name, a := range from {
+		sum, ok := into[name]
+		if !ok {
+			sum = &attemptStats{}
+			into[name] = sum
+		}
+		sum.merge(a)
+	}
+}
+
+// Record adds one call of op that took d and failed if err is non-nil.
+func (s *Stats) Record(op string, d time.Duration, err error) {
+	sh := s.shard()
+	defer sh.mu.Unlock()
+
+	for _, m := range []map[string]*opStats{sh.ops, sh.window} {
+		o, ok := m[op]
+		if !ok {
+			o = &opStats{}
+			m[op] = o
+		}
+		o.add(d, err)
+	}
+}
+
+// CredentialRefreshed counts a credential refresh expiring at expires.
+func (s *Stats) CredentialRefreshed(expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creds.refreshes++
+	s.windowCreds.refreshes++
+	s.credExpires = expires
+}
+
+// SecretsRedacted counts n secrets redacted from log output.
+func (s *Stats) SecretsRedacted(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redacted += n
+	s.windowRedacted += n
+}
+
+// CredentialFailed counts a failed credential retrieval.
+func (s *Stats) CredentialFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.creds.failures++
+	s.windowCreds.failures++
+}
+
+// RecordAttempts counts one SDK call to op that took attempts attempts.
+func (s *Stats) RecordAttempts(op string, attempts int) {
+	sh := s.shard()
+	defer sh.mu.Unlock()
+	a, ok := sh.attempts[op]
+	if !ok {
+		a = &attemptStats{}
+		sh.attempts[op] = a
+	}
+	a.calls++
+	a.attempts += attempts
+	if attempts > 1 {
+		a.retried++
+	}
+	a.max = max(a.max, attempts)
+}
+
+// CacheLookup counts a read cache lookup as a hit or a miss.
+func (s *Stats) CacheLookup(hit bool) {
+	sh := s.shard()
+	defer sh.mu.Unlock()
+	if hit {
+		sh.cache.hits++
+	} else {
+		sh.cache.misses++
+	}
+}
+
+// ConnectionUsed counts a request sent on a new connection, or on a
+// reused one that had been idle for idle.
+func (s *Stats) ConnectionUsed(reused bool, idle time.Duration) {
+	sh := s.shard()
+	defer sh.mu.Unlock()
+	if reused {
+		sh.conns.reused++
+		sh.conns.idle += idle
+	} else {
+		sh.conns.new++
+	}
+}
+
+// TakeWindow returns what was recorded since the previous call and
+// starts a new window.
+func (s *Stats) TakeWindow() Window {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window := make(map[string]*opStats)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		mergeOps(window, sh.window)
+		sh.window = make(map[string]*opStats)
+		sh.mu.Unlock()
+	}
+
+	w := Window{
+		Ops:                 make(map[string]OpSummary, len(window)),
+		CredentialRefreshes: s.windowCreds.refreshes,
+		CredentialFailures:  s.windowCreds.failures,
+		CredentialExpires:   s.credExpires,
+		RedactedSecrets:     s.windowRedacted,
+	}
+	for name, o := range window {
+		w.Ops[name] = OpSummary{Count: o.count, Errors: o.errors, Total: o.total, Min: o.min, Max: o.max}
+	}
+	s.windowCreds = credStats{}
+	s.windowRedacted = 0
+	return w
+}
+
+// IterationDone counts one completed loop iteration.
+func (s *Stats) IterationDone() {
+	s.mu.Lock()
+	s.iterations++
+	s.mu.Unlock()
+}
+
+// IterationFailed counts one loop iteration that failed with class.
+func (s *Stats) IterationFailed(class ErrorClass) {
+	s.mu.Lock()
+	if s.failures == nil {
+		s.failures = make(map[ErrorClass]int)
+	}
+	s.failures[class]++
+	s.mu.Unlock()
+}
+
+// ItemDamaged counts one item found missing, or else corrupt, under
+// -integrity.
+func (s *Stats) ItemDamaged(missing bool) {
+	s.mu.Lock()
+	if missing {
+		s.missing++
+	} else {
+		s.corrupt++
+	}
+	s.mu.Unlock()
+}
+
+// Report writes the summary table to w.
+func (s *Stats) Report(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "=== Summary ===\n")
+	fmt.Fprintf(w, "Iterations: %d, Elapsed: %s\n", s.iterations, time.Since(s.started).Round(time.Millisecond))
+	if len(s.failures) > 0 {
+		classes := make([]string, 0, len(s.failures))
+		total := 0
+		for class, n := range s.failures {
+			classes = append(classes, fmt.Sprintf("%s %d", class, n))
+			total += n
+		}
+		sort.Strings(classes)
+		fmt.Fprintf(w, "Failed iterations: %d (%s)\n", total, strings.Join(classes, ", "))
+	}
+	if s.redacted > 0 {
+		fmt.Fprintf(w, "WARNING: %d secrets were redacted from log output\n", s.redacted)
+	}
+	if s.corrupt+s.missing > 0 {
+		fmt.Fprintf(w, "Damaged items: %d corrupt, %d missing\n", s.corrupt, s.missing)
+	}
+	fmt.Fprintf(w, "Credential refreshes: %d, failures: %d\n", s.creds.refreshes, s.creds.failures)
+
+	ops := make(map[string]*opStats)
+	attempts := make(map[string]*attemptStats)
+	var cache cacheStats
+	var conns connStats
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		mergeOps(ops, sh.ops)
+		mergeAttempts(attempts, sh.attempts)
+		cache.hits += sh.cache.hits
+		cache.misses += sh.cache.misses
+		conns.new += sh.conns.new
+		conns.reused += sh.conns.reused
+		conns.idle += sh.conns.idle
+		sh.mu.Unlock()
+	}
+
+	if c := cache; c.hits+c.misses > 0 {
+		fmt.Fprintf(w, "Read cache: %d hits, %d misses (%.1f%% hit rate)\n",
+			c.hits, c.misses, 100*float64(c.hits)/float64(c.hits+c.misses))
+	}
+	if c := conns; c.new+c.reused > 0 {
+		var idle time.Duration
+		if c.reused > 0 {
+			idle = c.idle / time.Duration(c.reused)
+		}
+		fmt.Fprintf(w, "Connections: %d new, %d reused (%.1f%%), avg idle before reuse %s\n",
+			c.new, c.reused, 100*float64(c.reused)/float64(c.new+c.reused), idle.Round(time.Microsecond))
+	}
+
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tCOUNT\tERRORS\tAVG\tMIN\tMAX")
+	for _, name := range names {
+		o := ops[name]
+		avg := o.total / time.Duration(o.count)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n", name, o.count, o.errors,
+			avg.Round(time.Microsecond), o.min.Round(time.Microsecond), o.max.Round(time.Microsecond))
+	}
+	tw.Flush()
+
+	if len(attempts) == 0 {
+		return
+	}
+	names = names[:0]
+	for name := range attempts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(tw, "SDK CALL\tCALLS\tATTEMPTS\tRETRIED\tMAX ATTEMPTS")
+	for _, name := range names {
+		a := attempts[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", name, a.calls, a.attempts, a.retried, a.max)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,82 @@
+package obsv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BenchmarkStatsRecordParallel compares recording on one shard, as a
+// single mutex would, with recording across the shards; run it with
+// -cpu to see contention grow with the worker count.
+func BenchmarkStatsRecordParallel(b *testing.B) {
+	ops := []string{"PutItem", "GetItem", "BatchWriteItem", "Scan"}
+	for _, single := range []bool{true, false} {
+		name := "sharded"
+		if single {
+			name = "single"
+		}
+		b.Run(name, func(b *testing.B) {
+			s := NewStats()
+			if single {
+				s.shards, s.mask = s.shards[:1], 0
+			}
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					s.Record(ops[i%len(ops)], time.Millisecond, nil)
+				}
+			})
+		})
+	}
+}
+
+func TestStatsAggregatesShards(t *testing.T) {
+	s := NewStats()
+	failed := errors.New("failed")
+	done := make(chan struct{})
+	for g := range 8 {
+		go func() {
+			for i := range 1000 {
+				var err error
+				if i == 0 {
+					err = failed
+				}
+				s.Record("PutItem", time.Duration(g*1000+i+1)*time.Microsecond, err)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for range 8 {
+		<-done
+	}
+
+	got := s.TakeWindow().Ops["PutItem"]
+	if got.Count != 8000 || got.Errors != 8 || got.Min != time.Microsecond || got.Max != 8000*time.Microsecond {
+		t.Errorf("window %+v, want 8000 calls, 8 errors, min 1µs and max 8ms", got)
+	}
+	if again := s.TakeWindow().Ops["PutItem"]; again.Count != 0 {
+		t.Errorf("second window has %d calls, want a fresh window", again.Count)
+	}
+}
+
+func TestStatsReport(t *testing.T) {
+	s := NewStats()
+	s.Record("PutItem", 10*time.Millisecond, nil)
+	s.Record("PutItem", 30*time.Millisecond, errors.New("throttled"))
+	s.Record("GetItem", 5*time.Millisecond, nil)
+	s.IterationDone()
+	s.IterationDone()
+
+	var report strings.Builder
+	s.Report(&report)
+	for _, want := range []string{
+		"Iterations: 2,",
+		"GetItem    1      0       5ms   5ms   5ms",
+		"PutItem    2      1       20ms  10ms  30ms",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report does not contain %q:\n%s", want, report.String())
+		}
+	}
+}
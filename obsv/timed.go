@@ -0,0 +1,66 @@
+package obsv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Timeouts bound the calls made on behalf of a run: control-plane calls
+// such as CreateTable, and data-plane calls such as PutItem.
+type Timeouts struct {
+	Control time.Duration
+	Data    time.Duration
+}
+
+// Timed runs fn under a context bounded by timeout, records the call
+// as op in stats and returns its classified error. A call cut short by
+// the iteration's budget rather than its own timeout says so. A call
+// made while its Drain is draining is refused.
+func Timed(ctx context.Context, stats *Stats, op string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	finished, ok := admit(ctx)
+	if !ok {
+		return &HarnessError{Class: ClassUnavailable, Op: op, Err: ErrDraining}
+	}
+	defer finished()
+	opCtx, cancel := OpContext(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(opCtx)
+	stats.Record(op, time.Since(start), err)
+	var budgetErr *BudgetError
+	if err != nil && errors.As(context.Cause(ctx), &budgetErr) && !errors.As(err, &budgetErr) {
+		err = fmt.Errorf("%w: %w", budgetErr, err)
+	}
+	return Classify(op, err)
+}
+
+// OpContext derives the context for a single API call, bounded by timeout
+// unless it is zero.
+func OpContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// maxUnboundedWait is how long a waiter polls when neither its timeout
+// nor its context bounds it.
+const maxUnboundedWait = time.Hour
+
+// WaitTimeout returns the maximum wait for an SDK waiter polling under
+// ctx, which waiters require to be positive: timeout, or when timeout is
+// zero the time left before ctx's deadline, or failing that
+// maxUnboundedWait.
+func WaitTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// An expired context fails the waiter's first call on its own.
+		return max(time.Until(deadline), time.Millisecond)
+	}
+	return maxUnboundedWait
+}
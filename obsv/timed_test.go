@@ -0,0 +1,64 @@
+package obsv
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitTimeout(t *testing.T) {
+	deadline, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	for _, tc := range []struct {
+		name     string
+		ctx      context.Context
+		timeout  time.Duration
+		min, max time.Duration
+	}{
+		{"timeout", deadline, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		{"deadline", deadline, 0, 50 * time.Second, time.Minute},
+		{"expired", expired, 0, time.Millisecond, time.Millisecond},
+		{"unbounded", context.Background(), 0, maxUnboundedWait, maxUnboundedWait},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := WaitTimeout(tc.ctx, tc.timeout); got < tc.min || got > tc.max {
+				t.Errorf("WaitTimeout = %s, want between %s and %s", got, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+func TestTimed(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		timeout time.Duration
+		class   ErrorClass
+	}{
+		{"bounded", 20 * time.Millisecond, ClassUnavailable},
+		{"disabled", 0, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := NewStats()
+			err := Timed(context.Background(), stats, "CreateTable", tc.timeout, func(ctx context.Context) error {
+				_, bounded := ctx.Deadline()
+				if bounded != (tc.timeout > 0) {
+					t.Errorf("call has a deadline: %t, want %t", bounded, tc.timeout > 0)
+				}
+				if !bounded {
+					return nil
+				}
+				<-ctx.Done()
+				return ctx.Err()
+			})
+			if tc.class == "" && err != nil || tc.class != "" && ClassOf(err) != tc.class {
+				t.Errorf("Timed = %v, want class %q", err, tc.class)
+			}
+			if w := stats.TakeWindow(); w.Ops["CreateTable"].Count != 1 {
+				t.Errorf("recorded %+v, want one CreateTable call", w.Ops)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package workload
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchWrite is the most write requests one BatchWriteItem call takes.
+const maxBatchWrite = 25
+
+// batchWriter buffers items and writes them in batches once
+// maxBatchWrite are pending or the oldest has waited interval, so steady
+// and trickling producers both see bounded write latency. Batches are
+// written one at a time in the order their items were added. A batch
+// goes out even once the run is cancelled, so buffered items are never
+// dropped on shutdown, even while draining; each call is still bounded
+// by its own timeout.
+type batchWriter struct {
+	ctx      context.Context
+	interval time.Duration
+	write    func(ctx context.Context, requests []types.WriteRequest) error
+	// flushed is told how many items each successful batch wrote.
+	flushed func(n int) error
+
+	// flushing serializes flushes so batches complete in order.
+	flushing sync.Mutex
+
+	mu      sync.Mutex
+	pending []types.WriteRequest
+	timer   *time.Timer
+	err     error
+}
+
+func newBatchWriter(ctx context.Context, interval time.Duration,
+	write func(ctx context.Context, requests []types.WriteRequest) error, flushed func(n int) error) *batchWriter {
+	return &batchWriter{ctx: obsv.WithoutDrain(context.WithoutCancel(ctx)), interval: interval, write: write, flushed: flushed}
+}
+
+// Add buffers item, writing the batch if it is now full. It fails with
+// the error of any earlier batch, timed flushes included.
+func (b *batchWriter) Add(item map[string]types.AttributeValue) error {
+	b.mu.Lock()
+	if b.err != nil {
+		b.mu.Unlock()
+		return b.err
+	}
+	b.pending = append(b.pending, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+	full := len(b.pending) >= maxBatchWrite
+	if len(b.pending) == 1 && !full {
+		b.timer = time.AfterFunc(b.interval, func() { b.flush() })
+	}
+	b.mu.Unlock()
+
+	if full {
+		return b.flush()
+	}
+	return nil
+}
+
+// Close writes whatever is still buffered.
+func (b *batchWriter) Close() error {
+	return b.flush()
+}
+
+func (b *batchWriter) flush() error {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	err := b.err
+	b.mu.Unlock()
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+
+	err = b.write(b.ctx, batch)
+	if err == nil {
+		err = b.flushed(len(batch))
+	}
+	if err != nil {
+		b.mu.Lock()
+		b.err = err
+		b.mu.Unlock()
+	}
+	return err
+}
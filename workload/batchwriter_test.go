@@ -0,0 +1,124 @@
+package workload
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// batchRecorder collects the batches a batchWriter writes.
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches []int
+	items   []string
+}
+
+func (r *batchRecorder) write(ctx context.Context, requests []types.WriteRequest) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, len(requests))
+	for _, req := range requests {
+		r.items = append(r.items, req.PutRequest.Item["ID"].(*types.AttributeValueMemberS).Value)
+	}
+	return nil
+}
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+func testItem(n int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: strconv.Itoa(n)}}
+}
+
+func TestBatchWriterFlushesFullBatches(t *testing.T) {
+	r := &batchRecorder{}
+	written := 0
+	b := newBatchWriter(context.Background(), time.Hour, r.write, func(n int) error { written += n; return nil })
+	for n := range 60 {
+		if err := b.Add(testItem(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.sizes(); !slices.Equal(got, []int{25, 25}) {
+		t.Errorf("batches before Close %v, want two of 25", got)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.sizes(); !slices.Equal(got, []int{25, 25, 10}) || written != 60 {
+		t.Errorf("batches %v with %d written, want 25, 25 and 10 with 60 written", got, written)
+	}
+	for i, id := range r.items {
+		if id != strconv.Itoa(i) {
+			t.Fatalf("item %d written as %s, want items in the order added", i, id)
+		}
+	}
+}
+
+func TestBatchWriterFlushesAfterInterval(t *testing.T) {
+	r := &batchRecorder{}
+	b := newBatchWriter(context.Background(), 10*time.Millisecond, r.write, func(int) error { return nil })
+	for n := range 3 {
+		if err := b.Add(testItem(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.sizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := r.sizes(); !slices.Equal(got, []int{3}) {
+		t.Errorf("batches after the interval %v, want one of 3", got)
+	}
+}
+
+func TestBatchWriterFlushesAfterCancel(t *testing.T) {
+	r := &batchRecorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := newBatchWriter(ctx, time.Hour, r.write, func(int) error { return nil })
+	if err := b.Add(testItem(0)); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := b.Close(); err != nil {
+		t.Fatalf("close after cancel: %v", err)
+	}
+	if got := r.sizes(); !slices.Equal(got, []int{1}) {
+		t.Errorf("batches %v, want the buffered item written", got)
+	}
+}
+
+func TestBatchWriterFlushesWhileDraining(t *testing.T) {
+	r := &batchRecorder{}
+	drain := obsv.NewDrain()
+	ctx, cancel := drain.Bind(context.Background())
+	defer cancel()
+	write := func(ctx context.Context, requests []types.WriteRequest) error {
+		return obsv.Timed(ctx, obsv.NewStats(), "BatchWriteItem", 0, func(ctx context.Context) error {
+			return r.write(ctx, requests)
+		})
+	}
+	b := newBatchWriter(ctx, time.Hour, write, func(int) error { return nil })
+	if err := b.Add(testItem(0)); err != nil {
+		t.Fatal(err)
+	}
+	drain.Start(time.Hour)
+	if err := b.Close(); err != nil {
+		t.Fatalf("close while draining: %v", err)
+	}
+	if got := r.sizes(); !slices.Equal(got, []int{1}) {
+		t.Errorf("batches %v, want the buffered item written", got)
+	}
+}
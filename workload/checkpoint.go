@@ -0,0 +1,102 @@
+package workload
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// Workload phases recorded in the checkpoint.
+const (
+	phaseCreate = "create"
+	phaseSeed   = "seed"
+	phaseVerify = "verify"
+	phaseDone   = "done"
+)
+
+// checkpointEvery is how many items are written or verified between saves.
+const checkpointEvery = 100
+
+// Checkpoint is the persisted progress of a run. An interrupted run
+// started with -resume picks up from the recorded table and phase.
+type Checkpoint struct {
+	// Iteration is the number of fully completed iterations.
+	Iteration int `json:"iteration"`
+	// Table is the table the current iteration is working on.
+	Table string `json:"table,omitempty"`
+	// Phase is where the current iteration has got to.
+	Phase string `json:"phase"`
+	// Written and Verified count items seeded into and read back from Table.
+	Written  int `json:"written"`
+	Verified int `json:"verified"`
+	// Tables lists every table created by the run.
+	Tables []string `json:"tables,omitempty"`
+
+	path string
+}
+
+// NewCheckpoint returns an empty checkpoint persisted to path.
+// An empty path keeps progress in memory only.
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{Phase: phaseDone, path: path}
+}
+
+// LoadCheckpoint reads a checkpoint previously saved to path.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{path: path}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	if cp.Phase == "" {
+		return nil, errors.New("checkpoint has no phase")
+	}
+	return cp, nil
+}
+
+// startTable resets per-table progress for a newly created table.
+func (c *Checkpoint) startTable(name string) {
+	c.Table = name
+	c.Phase = phaseSeed
+	c.Written = 0
+	c.Verified = 0
+	c.Tables = append(c.Tables, name)
+}
+
+// reuseTable resets per-table progress to seed Table again.
+func (c *Checkpoint) reuseTable() {
+	c.Phase = phaseSeed
+	c.Written = 0
+	c.Verified = 0
+}
+
+// save writes the checkpoint atomically so a crash mid-write never
+// leaves a truncated file behind.
+func (c *Checkpoint) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
@@ -0,0 +1,56 @@
+package workload
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpointSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checkpoint.json")
+	cp := NewCheckpoint(path)
+	cp.startTable("MyTable1")
+	cp.Written, cp.Verified, cp.Iteration = 250, 100, 3
+	if err := cp.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, cp) {
+		t.Errorf("loaded %+v, want %+v", loaded, cp)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files left in the checkpoint directory, want only the checkpoint", len(entries))
+	}
+
+	for name, data := range map[string]string{"no phase": `{"iteration": 1}`, "not JSON": `iteration: 1`} {
+		bad := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(bad, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadCheckpoint(bad); err == nil {
+			t.Errorf("loading a checkpoint with %s succeeded", name)
+		}
+	}
+}
+
+func TestCheckpointTables(t *testing.T) {
+	cp := NewCheckpoint("")
+	cp.startTable("A")
+	cp.Written, cp.Verified = 10, 10
+	cp.reuseTable()
+	cp.startTable("B")
+
+	want := &Checkpoint{Table: "B", Phase: phaseSeed, Tables: []string{"A", "B"}}
+	if !reflect.DeepEqual(cp, want) {
+		t.Errorf("checkpoint %+v, want %+v", cp, want)
+	}
+	if err := cp.save(); err != nil {
+		t.Errorf("saving an in-memory checkpoint: %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// CleanupOptions select the leaked tables Cleanup deletes and pace it.
+type CleanupOptions struct {
+	// OlderThan spares tables created more recently, such as those of a
+	// run still in progress.
+	OlderThan time.Duration
+	// Workers is the most DeleteTable calls in flight at once.
+	Workers int
+	// DryRun lists the tables that would be deleted without deleting them.
+	DryRun bool
+}
+
+// CleanupResult counts what Cleanup did.
+type CleanupResult struct {
+	Found   int
+	Deleted int
+	Failed  int
+}
+
+// cleanupAttempts bounds the tries at deleting one table while the
+// account keeps reporting its control-plane limit exceeded.
+const cleanupAttempts = 8
+
+// cleanupBackoff is the first pause before retrying a deletion; it
+// doubles with each try up to 30 seconds.
+var cleanupBackoff = time.Second
+
+// Cleanup deletes the tables named with -table-prefix and older than
+// opts.OlderThan. Deletions run on a pool of opts.Workers goroutines
+// whose concurrency shrinks whenever the account reports too many
+// control-plane operations in progress and grows back as they succeed.
+// It fails with the class of the first table it could not delete.
+func Cleanup(ctx context.Context, h *Harness, opts CleanupOptions) (CleanupResult, error) {
+	client := h.DynamoClient()
+	tables, err := staleTables(ctx, h, client, opts.OlderThan)
+	if err != nil {
+		return CleanupResult{}, err
+	}
+	result := CleanupResult{Found: len(tables)}
+	h.Logger.Operationf("Cleanup found %d tables named %s* older than %s", len(tables), h.Flags.TablePrefix, opts.OlderThan)
+	if opts.DryRun {
+		for _, name := range tables {
+			h.Logger.Operationf("Would delete %s", name)
+		}
+		return result, nil
+	}
+
+	limit := newControlLimiter(opts.Workers)
+	names := make(chan string)
+	var mu sync.Mutex
+	var first error
+	var wg sync.WaitGroup
+	for range opts.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				err := deleteStaleTable(ctx, h, client, limit, name)
+				mu.Lock()
+				if err != nil {
+					result.Failed++
+					if first == nil {
+						first = err
+					}
+				} else {
+					result.Deleted++
+				}
+				if done := result.Deleted + result.Failed; done%25 == 0 || done == result.Found {
+					h.Logger.Operationf("Cleanup progress: %d of %d tables done, %d failed, %d deletions in flight allowed",
+						done, result.Found, result.Failed, limit.current())
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range tables {
+		select {
+		case names <- name:
+		case <-ctx.Done():
+		}
+	}
+	close(names)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil && first == nil {
+		first = err
+	}
+	if first != nil {
+		return result, obsv.Classify("Cleanup",
+			fmt.Errorf("%d of %d tables not deleted, first: %w", result.Found-result.Deleted, result.Found, first))
+	}
+	return result, nil
+}
+
+// staleTables lists the tables named with -table-prefix that were
+// created at least olderThan ago.
+func staleTables(ctx context.Context, h *Harness, client ddbtest.API, olderThan time.Duration) ([]string, error) {
+	var names []string
+	in := &dynamodb.ListTablesInput{}
+	for {
+		var out *dynamodb.ListTablesOutput
+		err := obsv.Timed(ctx, h.Stats, "ListTables", h.Flags.ControlTimeout, func(ctx context.Context) error {
+			var err error
+			out, err = client.ListTables(ctx, in)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range out.TableNames {
+			if strings.HasPrefix(name, h.Flags.TablePrefix) {
+				names = append(names, name)
+			}
+		}
+		if out.LastEvaluatedTableName == nil {
+			break
+		}
+		in.ExclusiveStartTableName = out.LastEvaluatedTableName
+	}
+	if olderThan <= 0 {
+		return names, nil
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	stale := names[:0]
+	for _, name := range names {
+		var out *dynamodb.DescribeTableOutput
+		err := obsv.Timed(ctx, h.Stats, "DescribeTable", h.Flags.ControlTimeout, func(ctx context.Context) error {
+			var err error
+			out, err = client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &name})
+			return err
+		})
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if created := aws.ToTime(out.Table.CreationDateTime); created.Before(cutoff) {
+			stale = append(stale, name)
+		}
+	}
+	return stale, nil
+}
+
+// deleteStaleTable deletes one table, backing off and retrying while the
+// account has too many control-plane operations in progress or the table
+// is busy. A table that is already gone counts as deleted.
+func deleteStaleTable(ctx context.Context, h *Harness, client ddbtest.API, limit *controlLimiter, name string) error {
+	backoff := cleanupBackoff
+	for attempt := 1; ; attempt++ {
+		if err := limit.acquire(ctx); err != nil {
+			return err
+		}
+		err := obsv.Timed(ctx, h.Stats, "DeleteTable", h.Flags.ControlTimeout, func(ctx context.Context) error {
+			_, err := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: &name})
+			return err
+		})
+		var notFound *types.ResourceNotFoundException
+		var inUse *types.ResourceInUseException
+		throttled := obsv.ClassOf(err) == obsv.ClassThrottling
+		limit.release(throttled)
+		switch {
+		case err == nil || errors.As(err, &notFound):
+			h.Logger.Operationf("Table deleted: %s", name)
+			return nil
+		case !throttled && !errors.As(err, &inUse), attempt == cleanupAttempts:
+			return err
+		}
+
+		// Full jitter keeps throttled workers from retrying in step.
+		select {
+		case <-time.After(rand.N(backoff)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// controlLimiter bounds concurrent control-plane calls adaptively: a
+// throttled call halves the bound and every other call raises it by one,
+// up to the size of the pool.
+type controlLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	max    int
+	active int
+}
+
+func newControlLimiter(max int) *controlLimiter {
+	l := &controlLimiter{limit: max, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire waits until a call may start or ctx is done.
+func (l *controlLimiter) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.cond.Broadcast()
+	})
+	defer stop()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= l.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l.cond.Wait()
+	}
+	l.active++
+	return nil
+}
+
+// release ends a call and adjusts the bound by how the call fared.
+func (l *controlLimiter) release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if throttled {
+		l.limit = max(1, l.limit/2)
+	} else {
+		l.limit = min(l.max, l.limit+1)
+	}
+	l.cond.Broadcast()
+}
+
+func (l *controlLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
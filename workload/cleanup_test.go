@@ -0,0 +1,134 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// createTables creates the named tables in the harness's memory store.
+func createTables(t *testing.T, h *Harness, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		_, err := h.Memory.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+			TableName:            aws.String(name),
+			AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("ID"), AttributeType: types.ScalarAttributeTypeS}},
+			KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: types.KeyTypeHash}},
+			BillingMode:          types.BillingModePayPerRequest,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func leakedTables(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("MyTable-%03d", i)
+	}
+	return names
+}
+
+func TestCleanupDeletesStaleTables(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-quiet")
+	createTables(t, h, leakedTables(130)...)
+	createTables(t, h, "OtherTable")
+	ctx := context.Background()
+
+	if result, err := Cleanup(ctx, h, CleanupOptions{OlderThan: time.Hour, Workers: 4}); err != nil || result.Found != 0 {
+		t.Fatalf("cleanup of fresh tables: %+v, %v; want none found", result, err)
+	}
+	if result, err := Cleanup(ctx, h, CleanupOptions{Workers: 4, DryRun: true}); err != nil || result.Found != 130 || result.Deleted != 0 {
+		t.Fatalf("dry run: %+v, %v; want 130 found and none deleted", result, err)
+	}
+
+	result, err := Cleanup(ctx, h, CleanupOptions{Workers: 4})
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if result != (CleanupResult{Found: 130, Deleted: 130}) {
+		t.Errorf("result %+v, want 130 found and deleted", result)
+	}
+	out, err := h.Memory.ListTables(ctx, &dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.TableNames) != 1 || out.TableNames[0] != "OtherTable" {
+		t.Errorf("tables left %v, want only OtherTable", out.TableNames)
+	}
+}
+
+// limitedDeletes fails the first throttle DeleteTable calls the way an
+// account over its control-plane limit does.
+type limitedDeletes struct {
+	*ddbtest.Memory
+	throttle int32
+	calls    atomic.Int32
+}
+
+func (l *limitedDeletes) DeleteTable(ctx context.Context, in *dynamodb.DeleteTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	if l.calls.Add(1) <= l.throttle {
+		return nil, &smithy.GenericAPIError{Code: "LimitExceededException", Message: "too many tables being deleted"}
+	}
+	return l.Memory.DeleteTable(ctx, in, optFns...)
+}
+
+func TestCleanupBacksOffWhenLimited(t *testing.T) {
+	defer func(d time.Duration) { cleanupBackoff = d }(cleanupBackoff)
+	cleanupBackoff = time.Millisecond
+
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-quiet")
+	createTables(t, h, leakedTables(20)...)
+	limited := &limitedDeletes{Memory: h.Memory, throttle: 6}
+	limit := newControlLimiter(8)
+	for _, name := range leakedTables(20) {
+		if err := deleteStaleTable(context.Background(), h, limited, limit, name); err != nil {
+			t.Fatalf("delete %s: %v", name, err)
+		}
+	}
+	if calls := limited.calls.Load(); calls != 26 {
+		t.Errorf("%d DeleteTable calls, want 26 with 6 retried", calls)
+	}
+
+	limited = &limitedDeletes{Memory: h.Memory, throttle: cleanupAttempts}
+	createTables(t, h, "MyTable-stuck")
+	if err := deleteStaleTable(context.Background(), h, limited, limit, "MyTable-stuck"); err == nil {
+		t.Errorf("delete succeeded after %d throttled attempts, want the throttling error", cleanupAttempts)
+	}
+}
+
+func TestControlLimiterAdapts(t *testing.T) {
+	l := newControlLimiter(8)
+	ctx := context.Background()
+	for range 3 {
+		if err := l.acquire(ctx); err != nil {
+			t.Fatal(err)
+		}
+		l.release(true)
+	}
+	if got := l.current(); got != 1 {
+		t.Fatalf("limit %d after three throttled calls, want 1", got)
+	}
+
+	if err := l.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	blocked, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(blocked); err == nil {
+		t.Fatal("second call started while the limit is 1")
+	}
+	l.release(false)
+	if got := l.current(); got != 2 {
+		t.Errorf("limit %d after a successful call, want 2", got)
+	}
+}
@@ -0,0 +1,600 @@
+package workload
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lou-Varndell/files/credlog"
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+// Config holds the settings parsed from the command line.
+type Config struct {
+	Verbosity obsv.Verbosity
+
+	// Modules lists the service workloads run on each iteration.
+	Modules []string
+
+	// Backend selects where DynamoDB calls go: "aws" for Endpoint, or
+	// "memory" for an in-process fake.
+	Backend string
+
+	// Endpoint is the base endpoint every client talks to.
+	Endpoint string
+	// Endpoints is Endpoint followed by the endpoints failed over to, in
+	// order.
+	Endpoints []string
+	// FailoverAfter is how many connection errors in a row move requests
+	// to the next of Endpoints.
+	FailoverAfter int
+	// Region is the signing and client region.
+	Region string
+
+	// SigningVersion forces the request signer; "auto" lets each
+	// operation choose.
+	SigningVersion string
+
+	// LocalStackContainer starts LocalStack in a container for the run and
+	// points Endpoint at it.
+	LocalStackContainer bool
+	// LocalStackImage is the image, including its version tag, to start.
+	LocalStackImage string
+
+	// Proxy, if set, overrides HTTP_PROXY and HTTPS_PROXY.
+	Proxy string
+	// NoProxy, if set, overrides NO_PROXY.
+	NoProxy string
+
+	// CABundle, if set, is a PEM file of extra CAs trusted for TLS.
+	CABundle string
+	// InsecureSkipVerify disables TLS certificate verification.
+	InsecureSkipVerify bool
+	// ClientCert and ClientKey are a PEM keypair presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+
+	// MaxIdleConnsPerHost is how many idle connections the HTTP client
+	// keeps open to each host for reuse.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes connections that have been idle this long.
+	IdleConnTimeout time.Duration
+	// DialTimeout bounds establishing a TCP connection.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake of a new connection.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for response headers once a
+	// request is written; 0 waits as long as the call's context allows.
+	ResponseHeaderTimeout time.Duration
+
+	// RetryMode is the SDK retry strategy, standard or adaptive.
+	RetryMode string
+	// RetryMaxAttempts is the most attempts made for one call, the
+	// first included.
+	RetryMaxAttempts int
+	// RetryMaxBackoff caps the delay between two attempts.
+	RetryMaxBackoff time.Duration
+	// RetryTokens is the size of the client's retry quota; 0 removes it.
+	RetryTokens uint
+	// RetryFailOnThrottle makes adaptive mode fail a call instead of
+	// waiting when its client-side send rate is used up.
+	RetryFailOnThrottle bool
+
+	// RecordPath, if set, is a cassette every HTTP exchange is written to.
+	RecordPath string
+	// ReplayPath, if set, is a cassette whose responses are served
+	// instead of contacting the endpoint.
+	ReplayPath string
+
+	// SSMPath, if set, is a Parameter Store path whose parameters supply
+	// flag values not given on the command line.
+	SSMPath string
+	// SSMPoll is how often SSMPath is re-read for workload changes; 0
+	// reads it once at startup.
+	SSMPoll time.Duration
+
+	// CredentialsSecret, if set, names a Secrets Manager secret holding
+	// the keypair to use; the static credentials only bootstrap access.
+	CredentialsSecret string
+	// SecretRefresh is how often the secret is re-read to catch rotation.
+	SecretRefresh time.Duration
+
+	// RoleARN, if set, is assumed with the static or secret credentials.
+	RoleARN string
+	// RoleSessionName names the assumed-role session.
+	RoleSessionName string
+	// RoleDuration is the lifetime requested for assumed-role credentials.
+	RoleDuration time.Duration
+	// SessionTags are attached to the assumed-role session.
+	SessionTags []credlog.SessionTag
+	// TransitiveTagKeys marks session tags that persist through role chaining.
+	TransitiveTagKeys []string
+
+	// Iterations bounds the number of loop iterations; 0 runs forever.
+	Iterations int
+	// Duration bounds the total run time; 0 runs forever.
+	Duration time.Duration
+	// Interval is the pause between iterations.
+	Interval time.Duration
+	// IterationBudget, if set, is the time each module's iteration may
+	// take, spent by its phases in turn.
+	IterationBudget time.Duration
+	// DrainTimeout is how long calls in flight may finish on shutdown
+	// before they are cancelled.
+	DrainTimeout time.Duration
+	// FailFast stops the run at the first failed iteration rather than
+	// counting it and carrying on with the next.
+	FailFast bool
+
+	// ReadyTimeout bounds how long the endpoint is probed before the
+	// first iteration; 0 skips the probe.
+	ReadyTimeout time.Duration
+
+	// ControlTimeout bounds control-plane calls such as CreateTable.
+	ControlTimeout time.Duration
+	// DataTimeout bounds data-plane calls such as PutItem and GetItem.
+	DataTimeout time.Duration
+
+	// TablePrefix begins the name of every table the dynamodb module creates.
+	TablePrefix string
+	// TableNaming is how the rest of a table's name is made: ulid,
+	// random or time. Streams, queues, topics and buckets are named the
+	// same way.
+	TableNaming string
+	// HashKey is the string hash key attribute of created tables.
+	HashKey string
+	// TableStreams enables a stream on each created table.
+	TableStreams bool
+	// TablePITR enables point-in-time recovery on each created table.
+	TablePITR bool
+	// Items is the number of items seeded into and verified from each table.
+	Items int
+	// WriteWorkers is the number of goroutines seeding items concurrently.
+	WriteWorkers int
+	// WriteQueue is the number of writes that may wait for a free worker
+	// before seeding blocks.
+	WriteQueue int
+	// WriteBatch is the number of items per write; above 1 the dynamodb
+	// module seeds with BatchWriteItem instead of PutItem.
+	WriteBatch int
+	// WriteTransact seeds with TransactWriteItems of WriteBatch items,
+	// each call carrying a ClientRequestToken derived from its items so
+	// a retried write is never applied twice.
+	WriteTransact bool
+	// WriteFlushInterval, if set, seeds through a buffer that is written
+	// with BatchWriteItem once it holds 25 items or its oldest item has
+	// waited this long.
+	WriteFlushInterval time.Duration
+	// CheckpointPath is where run progress is persisted; empty disables it.
+	CheckpointPath string
+	// Resume continues from the checkpoint at CheckpointPath.
+	Resume bool
+	// ReadCacheTTL, if set, puts a read-through GetItem cache that keeps
+	// items this long in front of the dynamodb module's client.
+	ReadCacheTTL time.Duration
+	// VerifyMode is how seeded items are read back: get fetches each
+	// item, scan streams the whole table and batch reads them with
+	// BatchGetItem.
+	VerifyMode string
+	// Integrity stores a checksum with every seeded item, verifies it on
+	// every read and counts corrupt and missing items instead of failing
+	// on the first.
+	Integrity bool
+	// IntegrityMaxDamaged is how many corrupt or missing items a run
+	// under -integrity tolerates before it fails.
+	IntegrityMaxDamaged int
+	// Strict checks the dynamodb module's responses against the requests
+	// they answer, failing on any divergence: read items must hold
+	// exactly the attributes written, and a new table's description must
+	// match its CreateTable request.
+	Strict bool
+	// ScanPageSize is the Limit of each Scan call under -verify-mode scan.
+	ScanPageSize int
+	// ScanBuffer is how many scanned pages may wait to be verified.
+	ScanBuffer int
+	// TablePerIteration creates a new table every iteration instead of
+	// seeding and verifying one table for the whole run.
+	TablePerIteration bool
+	// DeleteTables deletes each table once it has been verified, or the
+	// run's table once the loop ends.
+	DeleteTables bool
+	// SnapshotPath, if set, receives a dump of the run's tables at the end.
+	SnapshotPath string
+	// RestorePath, if set, is a snapshot whose tables are loaded before
+	// the first iteration.
+	RestorePath string
+
+	// S3ObjectSize is the size of the object uploaded by the s3 module.
+	S3ObjectSize int64
+	// S3PartSize is the multipart upload part size.
+	S3PartSize int64
+	// S3MultiRegionAccessPoint, if set, is an MRAP ARN the s3 module also
+	// writes and reads through, which requires SigV4a.
+	S3MultiRegionAccessPoint string
+
+	// SQSMessages is the number of messages sent per iteration.
+	SQSMessages int
+	// SQSBatchSize is the number of messages per SendMessageBatch call.
+	SQSBatchSize int
+	// SQSWait is the long-poll wait of each ReceiveMessage call.
+	SQSWait time.Duration
+	// SQSVisibilityTimeout hides received messages from other receivers.
+	SQSVisibilityTimeout time.Duration
+	// SQSProcessTime simulates work done on each received batch.
+	SQSProcessTime time.Duration
+
+	// SNSMessages is the number of messages published per iteration.
+	SNSMessages int
+
+	// KinesisShards is the shard count of each created stream.
+	KinesisShards int
+	// KinesisRecords is the number of records written per iteration.
+	KinesisRecords int
+	// KinesisPartitionKeys is the number of distinct partition keys used.
+	KinesisPartitionKeys int
+
+	// LambdaFunction is the function invoked by the lambda module.
+	LambdaFunction string
+	// LambdaInvocations is the number of sync and of async invocations per iteration.
+	LambdaInvocations int
+	// LambdaEcho expects synchronous results to carry the event id back.
+	LambdaEcho bool
+
+	// CloudWatchNamespace, if set, publishes harness metrics under it.
+	CloudWatchNamespace string
+	// CloudWatchInterval is how often metrics are published.
+	CloudWatchInterval time.Duration
+
+	// CloudWatchLogGroup, if set, ships the event stream to this log group.
+	CloudWatchLogGroup string
+	// CloudWatchLogStream is the stream events are written to.
+	CloudWatchLogStream string
+	// CloudWatchLogFlush is the longest events wait before being shipped.
+	CloudWatchLogFlush time.Duration
+
+	// EventsFile, if set, receives the event stream as JSON Lines.
+	EventsFile string
+	// EventsCompress compresses EventsFile: none, gzip or zstd.
+	EventsCompress string
+	// EventsFlush is the longest events wait before reaching EventsFile.
+	EventsFlush time.Duration
+	// IAMPolicyPath, if set, receives an IAM policy allowing exactly the
+	// calls the run made.
+	IAMPolicyPath string
+
+	// EventBridgeBus, if set, receives table lifecycle events.
+	EventBridgeBus string
+	// EventBridgeSource is the source of published lifecycle events.
+	EventBridgeSource string
+}
+
+// Signing versions accepted by -signing-version, as SDK auth scheme names.
+var signingVersions = []string{"auto", "sigv4", "sigv4a"}
+
+// verifyModes lists the values accepted by -verify-mode.
+var verifyModes = []string{"get", "scan", "batch"}
+
+// retryModes lists the values accepted by -retry-mode.
+var retryModes = []string{"standard", "adaptive"}
+
+// ParseFlags reads the command line into a Config. Commands with flags
+// of their own register them through extra.
+func ParseFlags(name string, args []string, extra ...func(fs *flag.FlagSet)) (*Config, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, register := range extra {
+		register(fs)
+	}
+
+	var verbose, veryVerbose, quiet bool
+	fs.BoolVar(&verbose, "v", false, "also log SDK requests, responses and retries")
+	fs.BoolVar(&veryVerbose, "vv", false, "also log full SDK wire traffic including bodies")
+	fs.BoolVar(&quiet, "quiet", false, "only log credential events")
+
+	cfg := &Config{Verbosity: obsv.VerbosityNormal}
+	modules := fs.String("modules", "dynamodb", "comma-separated `list` of modules to run ("+strings.Join(ModuleNames, ", ")+")")
+	fs.StringVar(&cfg.Backend, "backend", BackendAWS, "DynamoDB backend: "+BackendAWS+" uses -endpoint, "+BackendMemory+" an in-process fake supporting only the dynamodb module")
+	fs.StringVar(&cfg.Endpoint, "endpoint", "http://localhost:4566", "base `URL` of the AWS or LocalStack endpoint; a comma-separated list fails over in order")
+	fs.IntVar(&cfg.FailoverAfter, "failover-after", 3, "connection errors in a row before failing over to the next -endpoint")
+	fs.StringVar(&cfg.Region, "region", "us-west-2", "AWS `region`")
+	fs.BoolVar(&cfg.LocalStackContainer, "localstack-container", false, "start LocalStack in a container for the run and remove it afterwards")
+	fs.StringVar(&cfg.LocalStackImage, "localstack-image", "localstack/localstack:3.8", "`image` started by -localstack-container")
+	fs.StringVar(&cfg.SigningVersion, "signing-version", "auto", "request signer to prefer ("+strings.Join(signingVersions, ", ")+"); -v logs the signer per request")
+	fs.StringVar(&cfg.Proxy, "proxy", "", "send requests through this proxy `URL` (default from HTTPS_PROXY/HTTP_PROXY)")
+	fs.StringVar(&cfg.NoProxy, "no-proxy", "", "comma-separated `hosts` that bypass the proxy (default from NO_PROXY)")
+	fs.StringVar(&cfg.CABundle, "ca-bundle", "", "trust the CA certificates in this PEM `file` as well as the system roots")
+	fs.BoolVar(&cfg.InsecureSkipVerify, "insecure-skip-verify", false, "do not verify the endpoint's TLS certificate")
+	fs.StringVar(&cfg.ClientCert, "client-cert", "", "present this PEM certificate `file` for mutual TLS")
+	fs.StringVar(&cfg.ClientKey, "client-key", "", "private key `file` for -client-cert")
+	fs.IntVar(&cfg.MaxIdleConnsPerHost, "max-idle-conns-per-host", 10, "idle HTTP connections kept open to each host for reuse")
+	fs.DurationVar(&cfg.IdleConnTimeout, "idle-conn-timeout", 90*time.Second, "close HTTP connections idle this long (0 keeps them)")
+	fs.DurationVar(&cfg.DialTimeout, "dial-timeout", 30*time.Second, "timeout for establishing a TCP connection (0 disables)")
+	fs.DurationVar(&cfg.TLSHandshakeTimeout, "tls-handshake-timeout", 10*time.Second, "timeout for the TLS handshake of a new connection (0 disables)")
+	fs.DurationVar(&cfg.ResponseHeaderTimeout, "response-header-timeout", 0, "timeout for response headers once a request is written (0 disables)")
+	fs.StringVar(&cfg.RetryMode, "retry-mode", "standard", "SDK retry strategy ("+strings.Join(retryModes, ", ")+")")
+	fs.IntVar(&cfg.RetryMaxAttempts, "retry-max-attempts", 3, "most attempts per call, the first included")
+	fs.DurationVar(&cfg.RetryMaxBackoff, "retry-max-backoff", 20*time.Second, "longest delay between two attempts")
+	fs.UintVar(&cfg.RetryTokens, "retry-tokens", 500, "size of each client's retry quota token bucket (0 removes the quota)")
+	fs.BoolVar(&cfg.RetryFailOnThrottle, "retry-fail-on-throttle", false, "with -retry-mode adaptive, fail calls instead of waiting when the client-side send rate is used up")
+	fs.StringVar(&cfg.RecordPath, "record", "", "record every HTTP request and response to this cassette `file`")
+	fs.StringVar(&cfg.ReplayPath, "replay", "", "serve responses from this cassette `file` instead of the endpoint")
+	fs.StringVar(&cfg.SSMPath, "ssm-path", "", "read flag values from the SSM parameters under this `path`")
+	fs.DurationVar(&cfg.SSMPoll, "ssm-poll", 0, "re-read -ssm-path this often and apply workload changes (0 reads once)")
+	fs.StringVar(&cfg.CredentialsSecret, "credentials-secret", "", "read the access keypair from this Secrets Manager secret `id`")
+	fs.DurationVar(&cfg.SecretRefresh, "secret-refresh", 5*time.Minute, "how often -credentials-secret is re-read to pick up rotation")
+	fs.StringVar(&cfg.RoleARN, "role-arn", "", "assume this role with the static or secret credentials")
+	fs.StringVar(&cfg.RoleSessionName, "role-session-name", "harness", "session name used when assuming -role-arn")
+	fs.DurationVar(&cfg.RoleDuration, "role-duration", 15*time.Minute, "lifetime of assumed-role credentials")
+	fs.Func("session-tag", "attach a `key=value` session tag when assuming -role-arn (repeatable)", func(v string) error {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("want key=value, got %q", v)
+		}
+		cfg.SessionTags = append(cfg.SessionTags, credlog.SessionTag{Key: key, Value: value})
+		return nil
+	})
+	fs.Func("transitive-tag", "mark the session tag `key` as transitive (repeatable)", func(v string) error {
+		cfg.TransitiveTagKeys = append(cfg.TransitiveTagKeys, v)
+		return nil
+	})
+	fs.IntVar(&cfg.Iterations, "iterations", 0, "stop after `N` iterations (0 runs forever)")
+	fs.DurationVar(&cfg.Duration, "duration", 0, "stop after this much time has elapsed (0 runs forever)")
+	fs.DurationVar(&cfg.Interval, "interval", 2*time.Minute, "pause between iterations")
+	fs.DurationVar(&cfg.IterationBudget, "iteration-budget", 0, "time each module's iteration may take across all its calls, reporting the phase that ran out (0 disables)")
+	fs.DurationVar(&cfg.DrainTimeout, "drain-timeout", 30*time.Second, "on SIGINT or SIGTERM, wait this long for calls in flight and buffered batches to finish before cancelling them")
+	fs.BoolVar(&cfg.FailFast, "fail-fast", false, "stop the run at the first failed operation instead of moving on to the next iteration")
+	fs.DurationVar(&cfg.ReadyTimeout, "ready-timeout", time.Minute, "wait this long for the endpoint to become ready before starting (0 skips the probe)")
+	fs.DurationVar(&cfg.ControlTimeout, "control-timeout", 30*time.Second, "timeout for control-plane calls such as CreateTable (0 disables)")
+	fs.DurationVar(&cfg.DataTimeout, "data-timeout", 5*time.Second, "timeout for data-plane calls such as PutItem and GetItem (0 disables)")
+	fs.StringVar(&cfg.TablePrefix, "table-prefix", "MyTable", "prefix of the tables created by the dynamodb module")
+	fs.StringVar(&cfg.TableNaming, "table-naming", namingULID, "what follows -table-prefix in table names, and the prefix of other resources: ulid, random or time (HHMMSS, which collides between runs)")
+	fs.StringVar(&cfg.HashKey, "hash-key", "ID", "string hash key `attribute` of created tables")
+	fs.BoolVar(&cfg.TableStreams, "table-streams", false, "enable a NEW_AND_OLD_IMAGES stream on created tables (skipped where unsupported)")
+	fs.BoolVar(&cfg.TablePITR, "table-pitr", false, "enable point-in-time recovery on created tables (skipped where unsupported)")
+	fs.IntVar(&cfg.Items, "items", 1, "number of items to seed into and verify from each table")
+	fs.IntVar(&cfg.WriteWorkers, "write-workers", 1, "goroutines seeding items concurrently")
+	fs.IntVar(&cfg.WriteQueue, "write-queue", 0, "writes that may wait for a free worker before seeding blocks (0 means twice -write-workers)")
+	fs.IntVar(&cfg.WriteBatch, "write-batch", 1, "items per write; above 1 seeds with BatchWriteItem (1-25)")
+	fs.BoolVar(&cfg.WriteTransact, "write-transact", false, "seed with TransactWriteItems of -write-batch items, each idempotent under a deterministic client request token")
+	fs.DurationVar(&cfg.WriteFlushInterval, "write-flush-interval", 0, "seed through a buffer flushed with BatchWriteItem at 25 items or after this long (0 disables)")
+	fs.StringVar(&cfg.CheckpointPath, "checkpoint", "", "persist run progress to this `file`")
+	fs.BoolVar(&cfg.Resume, "resume", false, "resume from the -checkpoint file instead of starting over")
+	fs.DurationVar(&cfg.ReadCacheTTL, "read-cache-ttl", 0, "serve repeated GetItem reads from an in-process cache for this long (0 disables)")
+	fs.StringVar(&cfg.VerifyMode, "verify-mode", "get", "how seeded items are read back: get fetches each item, scan streams the whole table, batch uses BatchGetItem")
+	fs.BoolVar(&cfg.Integrity, "integrity", false, "store a checksum with every seeded item, verify it on every read and count corrupt and missing items")
+	fs.IntVar(&cfg.IntegrityMaxDamaged, "integrity-max-damaged", 0, "corrupt or missing items tolerated under -integrity before the run fails")
+	fs.BoolVar(&cfg.Strict, "strict", false, "fail on any DynamoDB response that diverges from the request it answers, such as an item read back with other attributes than were written")
+	fs.IntVar(&cfg.ScanPageSize, "scan-page-size", 1000, "items per Scan call under -verify-mode scan")
+	fs.IntVar(&cfg.ScanBuffer, "scan-buffer", 2, "scanned pages that may wait to be verified under -verify-mode scan")
+	fs.BoolVar(&cfg.TablePerIteration, "table-per-iteration", false, "create a new table every iteration instead of reusing one for the whole run")
+	fs.BoolVar(&cfg.DeleteTables, "delete-tables", false, "delete each table once its items have been verified (with one table per run, once the loop ends)")
+	fs.StringVar(&cfg.SnapshotPath, "snapshot", "", "write the run's tables and items to this `file` when the run completes")
+	fs.StringVar(&cfg.RestorePath, "restore", "", "create the tables in this snapshot `file` and load their items before starting")
+	fs.Int64Var(&cfg.S3ObjectSize, "s3-object-size", 12<<20, "size in `bytes` of the object uploaded by the s3 module")
+	fs.Int64Var(&cfg.S3PartSize, "s3-part-size", 5<<20, "multipart upload part size in `bytes` (at least 5 MiB)")
+	fs.StringVar(&cfg.S3MultiRegionAccessPoint, "s3-mrap", "", "also round-trip an object through this multi-region access point `ARN`")
+	fs.IntVar(&cfg.SQSMessages, "sqs-messages", 20, "messages sent per iteration by the sqs module")
+	fs.IntVar(&cfg.SQSBatchSize, "sqs-batch-size", 10, "messages per SendMessageBatch call (1-10)")
+	fs.DurationVar(&cfg.SQSWait, "sqs-wait", 10*time.Second, "long-poll wait of each ReceiveMessage call (at most 20s)")
+	fs.DurationVar(&cfg.SQSVisibilityTimeout, "sqs-visibility-timeout", 30*time.Second, "visibility timeout of received messages")
+	fs.DurationVar(&cfg.SQSProcessTime, "sqs-process-time", 0, "simulated processing time per received batch")
+	fs.IntVar(&cfg.SNSMessages, "sns-messages", 10, "messages published per iteration by the sns module")
+	fs.IntVar(&cfg.KinesisShards, "kinesis-shards", 2, "shard count of each stream created by the kinesis module")
+	fs.IntVar(&cfg.KinesisRecords, "kinesis-records", 100, "records written per iteration by the kinesis module")
+	fs.IntVar(&cfg.KinesisPartitionKeys, "kinesis-partition-keys", 8, "distinct partition keys records are spread over")
+	fs.StringVar(&cfg.LambdaFunction, "lambda-function", "", "`name` or ARN of the function invoked by the lambda module")
+	fs.IntVar(&cfg.LambdaInvocations, "lambda-invocations", 5, "sync and async invocations per iteration by the lambda module")
+	fs.BoolVar(&cfg.LambdaEcho, "lambda-echo", false, "expect the function to return its event, and check the id round-trips")
+	fs.StringVar(&cfg.CloudWatchNamespace, "cloudwatch-namespace", "", "publish harness metrics to CloudWatch under this `namespace`")
+	fs.DurationVar(&cfg.CloudWatchInterval, "cloudwatch-interval", time.Minute, "how often metrics are published to CloudWatch")
+	fs.StringVar(&cfg.CloudWatchLogGroup, "cloudwatch-log-group", "", "ship the event stream to this CloudWatch Logs `group`")
+	fs.StringVar(&cfg.CloudWatchLogStream, "cloudwatch-log-stream", "", "log stream to write to (default harness-<host>-<start time>)")
+	fs.DurationVar(&cfg.CloudWatchLogFlush, "cloudwatch-log-flush", 5*time.Second, "longest time events are buffered before shipping")
+	fs.StringVar(&cfg.EventsFile, "events-file", "", "write the event stream as JSON Lines to this `file`")
+	fs.StringVar(&cfg.EventsCompress, "events-compress", obsv.CompressNone, "compress -events-file: none, gzip or zstd")
+	fs.DurationVar(&cfg.EventsFlush, "events-flush", 5*time.Second, "longest time events are buffered before reaching -events-file")
+	fs.StringVar(&cfg.IAMPolicyPath, "iam-policy", "", "write an IAM policy allowing exactly the calls the run made to this `file`")
+	fs.StringVar(&cfg.EventBridgeBus, "eventbridge-bus", "", "publish table lifecycle events to this EventBridge `bus`")
+	fs.StringVar(&cfg.EventBridgeSource, "eventbridge-source", "dynamodb-harness", "source of published lifecycle events")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if cfg.Iterations < 0 {
+		return nil, fmt.Errorf("-iterations must not be negative")
+	}
+	if (len(cfg.SessionTags) > 0 || len(cfg.TransitiveTagKeys) > 0) && cfg.RoleARN == "" {
+		return nil, fmt.Errorf("-session-tag and -transitive-tag require -role-arn")
+	}
+	for _, k := range cfg.TransitiveTagKeys {
+		if !hasSessionTag(cfg.SessionTags, k) {
+			return nil, fmt.Errorf("-transitive-tag %q does not name a -session-tag", k)
+		}
+	}
+	if cfg.Endpoint == "" || cfg.Region == "" {
+		return nil, fmt.Errorf("-endpoint and -region must not be empty")
+	}
+	for _, e := range strings.Split(cfg.Endpoint, ",") {
+		u, err := url.Parse(strings.TrimSpace(e))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("-endpoint must be a comma-separated list of URLs such as http://localhost:4566")
+		}
+		cfg.Endpoints = append(cfg.Endpoints, u.String())
+	}
+	cfg.Endpoint = cfg.Endpoints[0]
+	if cfg.FailoverAfter < 1 {
+		return nil, fmt.Errorf("-failover-after must be at least 1")
+	}
+	if !slices.Contains(signingVersions, cfg.SigningVersion) {
+		return nil, fmt.Errorf("-signing-version must be one of %s", strings.Join(signingVersions, ", "))
+	}
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("-proxy must be a URL such as http://proxy:3128")
+		}
+	}
+	if cfg.RecordPath != "" && cfg.ReplayPath != "" {
+		return nil, fmt.Errorf("-record and -replay cannot be combined")
+	}
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, fmt.Errorf("-client-cert and -client-key must be given together")
+	}
+	if cfg.MaxIdleConnsPerHost < 1 {
+		return nil, fmt.Errorf("-max-idle-conns-per-host must be at least 1")
+	}
+	if cfg.IdleConnTimeout < 0 || cfg.DialTimeout < 0 || cfg.TLSHandshakeTimeout < 0 || cfg.ResponseHeaderTimeout < 0 {
+		return nil, fmt.Errorf("-idle-conn-timeout, -dial-timeout, -tls-handshake-timeout and -response-header-timeout must not be negative")
+	}
+	if !slices.Contains(retryModes, cfg.RetryMode) {
+		return nil, fmt.Errorf("-retry-mode must be one of %s", strings.Join(retryModes, ", "))
+	}
+	if cfg.RetryMaxAttempts < 1 || cfg.RetryMaxBackoff <= 0 {
+		return nil, fmt.Errorf("-retry-max-attempts must be at least 1 and -retry-max-backoff positive")
+	}
+	if cfg.RetryFailOnThrottle && cfg.RetryMode != "adaptive" {
+		return nil, fmt.Errorf("-retry-fail-on-throttle requires -retry-mode adaptive")
+	}
+	if cfg.SSMPoll < 0 || (cfg.SSMPoll > 0 && cfg.SSMPath == "") {
+		return nil, fmt.Errorf("-ssm-poll must not be negative and requires -ssm-path")
+	}
+	if cfg.TablePrefix == "" || cfg.HashKey == "" {
+		return nil, fmt.Errorf("-table-prefix and -hash-key must not be empty")
+	}
+	if !slices.Contains(namingStrategies, cfg.TableNaming) {
+		return nil, fmt.Errorf("-table-naming must be one of %s", strings.Join(namingStrategies, ", "))
+	}
+	if cfg.Items < 1 {
+		return nil, fmt.Errorf("-items must be at least 1")
+	}
+	if cfg.WriteWorkers < 1 || cfg.WriteQueue < 0 {
+		return nil, fmt.Errorf("-write-workers must be at least 1 and -write-queue must not be negative")
+	}
+	if cfg.WriteQueue == 0 {
+		cfg.WriteQueue = 2 * cfg.WriteWorkers
+	}
+	if cfg.WriteBatch < 1 || cfg.WriteBatch > maxBatchWrite {
+		return nil, fmt.Errorf("-write-batch must be between 1 and 25")
+	}
+	if cfg.WriteFlushInterval < 0 || (cfg.WriteFlushInterval > 0 && (cfg.WriteWorkers > 1 || cfg.WriteBatch > 1 || cfg.WriteTransact)) {
+		return nil, fmt.Errorf("-write-flush-interval must not be negative and cannot be combined with -write-workers, -write-batch or -write-transact")
+	}
+	if cfg.ReadCacheTTL < 0 {
+		return nil, fmt.Errorf("-read-cache-ttl must not be negative")
+	}
+	if !slices.Contains(verifyModes, cfg.VerifyMode) {
+		return nil, fmt.Errorf("-verify-mode must be one of %s", strings.Join(verifyModes, ", "))
+	}
+	if cfg.IntegrityMaxDamaged < 0 {
+		return nil, fmt.Errorf("-integrity-max-damaged must not be negative")
+	}
+	if cfg.ScanPageSize < 1 || cfg.ScanPageSize > math.MaxInt32 || cfg.ScanBuffer < 0 {
+		return nil, fmt.Errorf("-scan-page-size must be positive and -scan-buffer must not be negative")
+	}
+	if cfg.Resume && cfg.CheckpointPath == "" {
+		return nil, fmt.Errorf("-resume requires -checkpoint")
+	}
+	if cfg.S3ObjectSize < 1 {
+		return nil, fmt.Errorf("-s3-object-size must be positive")
+	}
+	if cfg.S3PartSize < 5<<20 {
+		return nil, fmt.Errorf("-s3-part-size must be at least 5 MiB")
+	}
+	if cfg.SQSMessages < 1 {
+		return nil, fmt.Errorf("-sqs-messages must be at least 1")
+	}
+	if cfg.SQSBatchSize < 1 || cfg.SQSBatchSize > 10 {
+		return nil, fmt.Errorf("-sqs-batch-size must be between 1 and 10")
+	}
+	if cfg.SQSWait < 0 || cfg.SQSWait > 20*time.Second {
+		return nil, fmt.Errorf("-sqs-wait must be between 0 and 20s")
+	}
+	if cfg.SQSVisibilityTimeout < time.Second || cfg.SQSProcessTime < 0 {
+		return nil, fmt.Errorf("-sqs-visibility-timeout must be at least 1s and -sqs-process-time not negative")
+	}
+	if cfg.SNSMessages < 1 {
+		return nil, fmt.Errorf("-sns-messages must be at least 1")
+	}
+	if cfg.KinesisShards < 1 || cfg.KinesisRecords < 1 || cfg.KinesisPartitionKeys < 1 {
+		return nil, fmt.Errorf("-kinesis-shards, -kinesis-records and -kinesis-partition-keys must be at least 1")
+	}
+	if cfg.LambdaInvocations < 1 {
+		return nil, fmt.Errorf("-lambda-invocations must be at least 1")
+	}
+
+	for _, m := range strings.Split(*modules, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			cfg.Modules = append(cfg.Modules, m)
+		}
+	}
+	if len(cfg.Modules) == 0 {
+		return nil, fmt.Errorf("-modules must name at least one module")
+	}
+	switch cfg.Backend {
+	case BackendAWS:
+	case BackendMemory:
+		for _, m := range cfg.Modules {
+			if m != "dynamodb" {
+				return nil, fmt.Errorf("-backend %s supports only the dynamodb module, not %s", BackendMemory, m)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("-backend must be %s or %s", BackendAWS, BackendMemory)
+	}
+	if cfg.SecretRefresh <= 0 {
+		return nil, fmt.Errorf("-secret-refresh must be positive")
+	}
+	if cfg.CloudWatchInterval <= 0 || cfg.CloudWatchLogFlush <= 0 {
+		return nil, fmt.Errorf("-cloudwatch-interval and -cloudwatch-log-flush must be positive")
+	}
+	switch cfg.EventsCompress {
+	case obsv.CompressNone, obsv.CompressGzip, obsv.CompressZstd:
+	default:
+		return nil, fmt.Errorf("-events-compress must be %s, %s or %s", obsv.CompressNone, obsv.CompressGzip, obsv.CompressZstd)
+	}
+	if cfg.EventsFlush <= 0 {
+		return nil, fmt.Errorf("-events-flush must be positive")
+	}
+	if cfg.CloudWatchLogStream == "" {
+		host, _ := os.Hostname()
+		if host == "" {
+			host = "unknown"
+		}
+		cfg.CloudWatchLogStream = "harness-" + host + "-" + time.Now().UTC().Format("20060102T150405Z")
+	}
+	if cfg.Duration < 0 || cfg.Interval < 0 || cfg.IterationBudget < 0 || cfg.DrainTimeout < 0 || cfg.ReadyTimeout < 0 || cfg.ControlTimeout < 0 || cfg.DataTimeout < 0 {
+		return nil, fmt.Errorf("durations must not be negative")
+	}
+
+	switch {
+	case quiet && (verbose || veryVerbose):
+		return nil, fmt.Errorf("-quiet cannot be combined with -v or -vv")
+	case quiet:
+		cfg.Verbosity = obsv.VerbosityQuiet
+	case veryVerbose:
+		cfg.Verbosity = obsv.VerbosityDebug
+	case verbose:
+		cfg.Verbosity = obsv.VerbosityVerbose
+	}
+
+	return cfg, nil
+}
+
+// Timeouts returns -control-timeout and -data-timeout.
+func (c *Config) Timeouts() obsv.Timeouts {
+	return obsv.Timeouts{Control: c.ControlTimeout, Data: c.DataTimeout}
+}
+
+func hasSessionTag(tags []credlog.SessionTag, key string) bool {
+	for _, t := range tags {
+		if t.Key == key {
+			return true
+		}
+	}
+	return false
+}
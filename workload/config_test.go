@@ -0,0 +1,106 @@
+package workload
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Lou-Varndell/files/obsv"
+)
+
+func TestVerbosityFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want obsv.Verbosity
+		err  bool
+	}{
+		{args: nil, want: obsv.VerbosityNormal},
+		{args: []string{"-quiet"}, want: obsv.VerbosityQuiet},
+		{args: []string{"-v"}, want: obsv.VerbosityVerbose},
+		{args: []string{"-vv"}, want: obsv.VerbosityDebug},
+		{args: []string{"-v", "-vv"}, want: obsv.VerbosityDebug},
+		{args: []string{"-quiet", "-v"}, err: true},
+	} {
+		cfg, err := ParseFlags("harness", tc.args)
+		switch {
+		case tc.err:
+			if err == nil {
+				t.Errorf("ParseFlags(%q) succeeded, want an error", tc.args)
+			}
+		case err != nil:
+			t.Errorf("ParseFlags(%q): %v", tc.args, err)
+		case cfg.Verbosity != tc.want:
+			t.Errorf("ParseFlags(%q) verbosity %d, want %d", tc.args, cfg.Verbosity, tc.want)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		want []string
+		err  bool
+	}{
+		{name: "default", want: []string{"dynamodb"}},
+		{name: "list", args: []string{"-modules", "dynamodb, s3,,sqs"}, want: []string{"dynamodb", "s3", "sqs"}},
+		{name: "unknown", args: []string{"-modules", "dynamodb,ftp"}, err: true},
+		{name: "empty", args: []string{"-modules", " , "}, err: true},
+		{name: "memory backend", args: []string{"-backend", "memory", "-modules", "s3"}, err: true},
+		{name: "small parts", args: []string{"-modules", "s3", "-s3-part-size", "1048576"}, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			cfg, err := ParseFlags("harness", tc.args)
+			if err == nil {
+				var workloads []Workload
+				workloads, err = NewWorkloads(&Harness{Flags: cfg})
+				for _, w := range workloads {
+					names = append(names, w.Name())
+				}
+			}
+			if tc.err != (err != nil) || !slices.Equal(names, tc.want) {
+				t.Errorf("modules %q, error %v; want %q, error %t", names, err, tc.want, tc.err)
+			}
+		})
+	}
+}
+
+func TestSessionTagFlags(t *testing.T) {
+	role := []string{"-role-arn", "arn:aws:iam::000000000000:role/harness"}
+	for _, tc := range []struct {
+		name string
+		args []string
+		tags int
+		err  bool
+	}{
+		{name: "tags", args: append(role, "-session-tag", "team=storage", "-session-tag", "env=", "-transitive-tag", "team"), tags: 2},
+		{name: "no value", args: append(role, "-session-tag", "team"), err: true},
+		{name: "no key", args: append(role, "-session-tag", "=storage"), err: true},
+		{name: "no role", args: []string{"-session-tag", "team=storage"}, err: true},
+		{name: "transitive without tag", args: append(role, "-transitive-tag", "team"), err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParseFlags("harness", tc.args)
+			if tc.err != (err != nil) || err == nil && len(cfg.SessionTags) != tc.tags {
+				t.Errorf("ParseFlags(%q) = %+v, %v; want %d tags, error %t", tc.args, cfg, err, tc.tags, tc.err)
+			}
+		})
+	}
+}
+
+func TestSigningVersionFlag(t *testing.T) {
+	for _, tc := range []struct {
+		version string
+		err     bool
+	}{
+		{"auto", false},
+		{"sigv4", false},
+		{"sigv4a", false},
+		{"sigv2", true},
+	} {
+		cfg, err := ParseFlags("harness", []string{"-signing-version", tc.version})
+		if tc.err != (err != nil) || err == nil && cfg.SigningVersion != tc.version {
+			t.Errorf("-signing-version %s: %+v, %v; want error %t", tc.version, cfg, err, tc.err)
+		}
+	}
+}
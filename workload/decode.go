@@ -0,0 +1,105 @@
+package workload
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrItemNotFound is the cause of a mismatch where a read found no item.
+var ErrItemNotFound = errors.New("item not found")
+
+// AttributeError is the cause of a mismatch where a read item's
+// attribute is missing, NULL or of another type than was written.
+type AttributeError struct {
+	Name string
+	// Want and Got are DynamoDB type descriptors such as S or NULL; Got
+	// is empty if the attribute is missing.
+	Want string
+	Got  string
+}
+
+func (e *AttributeError) Error() string {
+	if e.Got == "" {
+		return fmt.Sprintf("attribute %s is missing, want type %s", e.Name, e.Want)
+	}
+	return fmt.Sprintf("attribute %s has type %s, want %s", e.Name, e.Got, e.Want)
+}
+
+// attributeType returns the type descriptor of v, or "" for nil.
+func attributeType(v types.AttributeValue) string {
+	switch v.(type) {
+	case nil:
+		return ""
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// stringAttribute returns the string attribute name of item.
+func stringAttribute(item map[string]types.AttributeValue, name string) (string, error) {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok && v != nil {
+		return v.Value, nil
+	}
+	return "", &AttributeError{Name: name, Want: "S", Got: attributeType(item[name])}
+}
+
+// binaryAttribute returns the binary attribute name of item.
+func binaryAttribute(item map[string]types.AttributeValue, name string) ([]byte, error) {
+	if v, ok := item[name].(*types.AttributeValueMemberB); ok && v != nil {
+		return v.Value, nil
+	}
+	return nil, &AttributeError{Name: name, Want: "B", Got: attributeType(item[name])}
+}
+
+// checkSeededItem returns a mismatch for op unless item, read back for
+// id, is the item the dynamodb module seeded, with its checksum intact
+// if checksum is set.
+func checkSeededItem(op, id string, item map[string]types.AttributeValue, checksum bool) error {
+	if err := seededItemFault(item, checksum); err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassMismatch, Op: op, Err: fmt.Errorf("item %s: %w", id, err)}
+	}
+	return nil
+}
+
+// seededItemFault returns what is wrong with item as a seeded item.
+func seededItemFault(item map[string]types.AttributeValue, checksum bool) error {
+	if item == nil {
+		return ErrItemNotFound
+	}
+	if checksum {
+		if err := checkChecksum(item); err != nil {
+			return err
+		}
+	}
+	name, err := stringAttribute(item, "Name")
+	if err != nil {
+		return err
+	}
+	if name != "LocalUser" {
+		return fmt.Errorf("expected Name=%q, got %q", "LocalUser", name)
+	}
+	return nil
+}
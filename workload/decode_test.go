@@ -0,0 +1,63 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestCheckSeededItem(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		item     map[string]types.AttributeValue
+		notFound bool
+		got      string // the AttributeError's Got, if one is expected
+		mismatch bool
+	}{
+		{name: "seeded", item: map[string]types.AttributeValue{"Name": &types.AttributeValueMemberS{Value: "LocalUser"}}},
+		{name: "absent item", notFound: true, mismatch: true},
+		{name: "missing attribute", item: map[string]types.AttributeValue{}, mismatch: true},
+		{name: "NULL", item: map[string]types.AttributeValue{"Name": &types.AttributeValueMemberNULL{Value: true}}, got: "NULL", mismatch: true},
+		{name: "number", item: map[string]types.AttributeValue{"Name": &types.AttributeValueMemberN{Value: "7"}}, got: "N", mismatch: true},
+		{name: "nil member", item: map[string]types.AttributeValue{"Name": (*types.AttributeValueMemberS)(nil)}, got: "S", mismatch: true},
+		{name: "other value", item: map[string]types.AttributeValue{"Name": &types.AttributeValueMemberS{Value: "Someone"}}, mismatch: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSeededItem("GetItem", "1", tc.item, false)
+			if (err != nil) != tc.mismatch {
+				t.Fatalf("got %v, want mismatch %v", err, tc.mismatch)
+			}
+			if err == nil {
+				return
+			}
+			var he *obsv.HarnessError
+			if !errors.As(err, &he) || he.Class != obsv.ClassMismatch {
+				t.Errorf("%v is not classed %s", err, obsv.ClassMismatch)
+			}
+			if errors.Is(err, ErrItemNotFound) != tc.notFound {
+				t.Errorf("%v: item not found is %v, want %v", err, !tc.notFound, tc.notFound)
+			}
+			var attrErr *AttributeError
+			if errors.As(err, &attrErr) && attrErr.Got != tc.got {
+				t.Errorf("%v: attribute type %q, want %q", err, attrErr.Got, tc.got)
+			}
+		})
+	}
+}
+
+func TestMemoryGetMissingItem(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	ctx := context.Background()
+	if err := w.createTable(ctx, "MyTableEmpty"); err != nil {
+		t.Fatal(err)
+	}
+	err := w.getItem(ctx, "MyTableEmpty", 0)
+	if !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("reading an item never written: %v, want ErrItemNotFound", err)
+	}
+}
@@ -0,0 +1,229 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func TestMemoryTableLifecycle(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "250", "-table-per-iteration", "-delete-tables", "-quiet")
+	ctx := context.Background()
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+
+	if err := w.RunIteration(ctx); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	cp := h.Checkpoint
+	if cp.Phase != phaseDone || cp.Written != 250 || cp.Verified != 250 {
+		t.Fatalf("checkpoint %+v, want phase done with 250 written and verified", cp)
+	}
+
+	_, err := w.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &cp.Table})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Errorf("table %s still exists after -delete-tables: %v", cp.Table, err)
+	}
+}
+
+func TestMemoryTableReuse(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "5",
+		"-iterations", "3", "-interval", "0", "-delete-tables", "-quiet")
+	workloads, err := NewWorkloads(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunLoop(context.Background(), h, workloads); err != nil {
+		t.Fatalf("run loop: %v", err)
+	}
+
+	ops := h.Stats.TakeWindow().Ops
+	if ops["CreateTable"].Count != 1 || ops["DeleteTable"].Count != 1 || ops["PutItem"].Count != 15 {
+		t.Errorf("ops %+v, want one table created and deleted and 15 items written", ops)
+	}
+	if cp := h.Checkpoint; len(cp.Tables) != 1 || cp.Table != "" {
+		t.Errorf("checkpoint %+v, want one table created and none left", cp)
+	}
+}
+
+func TestMemoryVerifyScan(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "25",
+		"-verify-mode", "scan", "-scan-page-size", "10", "-scan-buffer", "1", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	if err := w.RunIteration(context.Background()); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	ops := h.Stats.TakeWindow().Ops
+	if h.Checkpoint.Verified != 25 || ops["Scan"].Count != 3 || ops["GetItem"].Count != 0 {
+		t.Errorf("verified %d items with %d scans and %d gets, want 25 with 3 scans and no gets",
+			h.Checkpoint.Verified, ops["Scan"].Count, ops["GetItem"].Count)
+	}
+}
+
+func TestMemoryConcurrentSeed(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "257",
+		"-write-workers", "8", "-write-queue", "2", "-write-batch", "10", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	if err := w.RunIteration(context.Background()); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	cp := h.Checkpoint
+	if cp.Written != 257 || cp.Verified != 257 {
+		t.Errorf("checkpoint %+v, want 257 written and verified", cp)
+	}
+	if n := h.Stats.TakeWindow().Ops["BatchWriteItem"].Count; n != 26 {
+		t.Errorf("%d BatchWriteItem calls, want 26", n)
+	}
+}
+
+func TestMemoryBufferedSeed(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "60",
+		"-write-flush-interval", "1s", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	if err := w.RunIteration(context.Background()); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	if cp := h.Checkpoint; cp.Written != 60 || cp.Verified != 60 {
+		t.Errorf("checkpoint %+v, want 60 written and verified", cp)
+	}
+	if n := h.Stats.TakeWindow().Ops["BatchWriteItem"].Count; n != 3 {
+		t.Errorf("%d BatchWriteItem calls, want 3", n)
+	}
+}
+
+// lostResponses applies every TransactWriteItems call but loses the
+// response to the first attempt at each, the way a dropped connection
+// does, and records the tokens the calls carried.
+type lostResponses struct {
+	*ddbtest.Memory
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (l *lostResponses) TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	out, err := l.Memory.TransactWriteItems(ctx, in, optFns...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	token := aws.ToString(in.ClientRequestToken)
+	l.tokens = append(l.tokens, token)
+	if err == nil && (len(l.tokens) == 1 || l.tokens[len(l.tokens)-2] != token) {
+		return nil, &smithyhttp.RequestSendError{Err: errors.New("connection reset by peer")}
+	}
+	return out, err
+}
+
+func TestMemoryTransactSeedRetriesIdempotently(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "60",
+		"-write-batch", "25", "-write-transact", "-quiet")
+	client := &lostResponses{Memory: h.Memory}
+	w := &dynamoWorkload{Harness: h, client: client}
+	if err := w.RunIteration(context.Background()); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	if h.Checkpoint.Verified != 60 {
+		t.Errorf("verified %d items, want 60", h.Checkpoint.Verified)
+	}
+
+	// Three writes, each sent twice under one token of its own.
+	tokens := client.tokens
+	if len(tokens) != 6 || tokens[0] != tokens[1] || tokens[2] != tokens[3] || tokens[4] != tokens[5] ||
+		tokens[0] == tokens[2] || tokens[2] == tokens[4] {
+		t.Fatalf("tokens %q, want each of three writes retried under its own token", tokens)
+	}
+	if want := requestToken(h.Checkpoint.Table, 1, 0, 25); tokens[0] != want || len(want) > 36 {
+		t.Errorf("first token %q, want the deterministic %q of at most 36 characters", tokens[0], want)
+	}
+}
+
+func TestMemoryBackendRejectsOtherModules(t *testing.T) {
+	if _, err := ParseFlags("harness", []string{"-backend", "memory", "-modules", "dynamodb,s3"}); err == nil {
+		t.Error("-backend memory accepted the s3 module")
+	}
+}
+
+func TestMemoryScanPages(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "7", "-quiet")
+	ctx := context.Background()
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	if err := w.RunIteration(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var start map[string]types.AttributeValue
+	seen := 0
+	for pages := 1; ; pages++ {
+		out, err := w.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         &h.Checkpoint.Table,
+			Limit:             aws.Int32(3),
+			ExclusiveStartKey: start,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen += len(out.Items)
+		if out.LastEvaluatedKey == nil {
+			if seen != 7 || pages != 3 {
+				t.Errorf("scanned %d items in %d pages, want 7 in 3", seen, pages)
+			}
+			return
+		}
+		start = out.LastEvaluatedKey
+	}
+}
+
+func BenchmarkPutItem(b *testing.B) {
+	w := newBenchWorkload(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for n := 0; b.Loop(); n++ {
+		if err := w.putItem(ctx, w.Checkpoint.Table, n%1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetItem(b *testing.B) {
+	w := newBenchWorkload(b)
+	ctx := context.Background()
+	for n := range 1000 {
+		if err := w.putItem(ctx, w.Checkpoint.Table, n); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportAllocs()
+	for n := 0; b.Loop(); n++ {
+		if err := w.getItem(ctx, w.Checkpoint.Table, n%1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// newBenchWorkload returns a dynamodb workload on the memory backend
+// with its table created.
+func newBenchWorkload(b *testing.B) *dynamoWorkload {
+	flags, err := ParseFlags("harness", []string{"-endpoint", ddbtest.Offline, "-backend", "memory", "-quiet"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	h := &Harness{
+		Flags:      flags,
+		Logger:     &obsv.Logger{Verbosity: flags.Verbosity},
+		Stats:      obsv.NewStats(),
+		Checkpoint: NewCheckpoint(""),
+		Memory:     ddbtest.NewMemory(),
+	}
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	h.Checkpoint.Table = flags.TablePrefix + "Bench"
+	if err := w.createTable(context.Background(), h.Checkpoint.Table); err != nil {
+		b.Fatal(err)
+	}
+	return w
+}
@@ -0,0 +1,74 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// Table lifecycle detail types published to EventBridge.
+const (
+	tableCreated  = "Table Created"
+	tableSeeded   = "Table Seeded"
+	tableVerified = "Table Verified"
+	tableDeleted  = "Table Deleted"
+)
+
+// tableEvent is the detail of a table lifecycle event.
+type tableEvent struct {
+	Table     string `json:"table"`
+	Items     int    `json:"items"`
+	Iteration int    `json:"iteration"`
+}
+
+// LifecyclePublisher sends table lifecycle events to an EventBridge bus
+// so test automation can react without polling. A nil publisher is a
+// no-op.
+type LifecyclePublisher struct {
+	*Harness
+	client *eventbridge.Client
+}
+
+// NewLifecyclePublisher returns a publisher to -eventbridge-bus.
+func NewLifecyclePublisher(h *Harness) *LifecyclePublisher {
+	return &LifecyclePublisher{
+		Harness: h,
+		client:  eventbridge.NewFromConfig(h.AWS),
+	}
+}
+
+// publish sends one lifecycle event. Failures are logged and counted but
+// never fail the workload.
+func (p *LifecyclePublisher) publish(ctx context.Context, detailType string, detail tableEvent) {
+	if p == nil {
+		return
+	}
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return
+	}
+
+	err = obsv.Timed(ctx, p.Stats, "PutEvents", p.Flags.DataTimeout, func(ctx context.Context) error {
+		out, err := p.client.PutEvents(ctx, &eventbridge.PutEventsInput{
+			Entries: []types.PutEventsRequestEntry{{
+				EventBusName: &p.Flags.EventBridgeBus,
+				Source:       &p.Flags.EventBridgeSource,
+				DetailType:   aws.String(detailType),
+				Detail:       aws.String(string(data)),
+			}},
+		})
+		if err == nil && out.FailedEntryCount > 0 {
+			err = fmt.Errorf("entry rejected: %s %s",
+				aws.ToString(out.Entries[0].ErrorCode), aws.ToString(out.Entries[0].ErrorMessage))
+		}
+		return err
+	})
+	if err != nil {
+		p.Logger.Operationf("Failed to publish %q for %s: %v", detailType, detail.Table, err)
+	}
+}
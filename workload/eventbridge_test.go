@@ -0,0 +1,61 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLifecyclePublish(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		failed bool
+		errors int
+	}{
+		{"accepted", false, 0},
+		{"entry rejected", true, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var entries []struct {
+				EventBusName, Source, DetailType, Detail string
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var in struct {
+					Entries []struct{ EventBusName, Source, DetailType, Detail string }
+				}
+				if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+					t.Errorf("decode PutEvents: %v", err)
+				}
+				entries = append(entries, in.Entries...)
+				out := map[string]any{"FailedEntryCount": 0, "Entries": []map[string]string{{"EventId": "1"}}}
+				if tc.failed {
+					out = map[string]any{"FailedEntryCount": 1, "Entries": []map[string]string{{"ErrorCode": "InternalFailure"}}}
+				}
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+				json.NewEncoder(w).Encode(out)
+			}))
+			defer srv.Close()
+
+			h, _ := newTestHarness(t, srv.URL, "-eventbridge-bus", "harness-bus", "-quiet")
+			NewLifecyclePublisher(h).publish(context.Background(), tableCreated, tableEvent{Table: "MyTable", Items: 3, Iteration: 2})
+
+			if len(entries) != 1 {
+				t.Fatalf("published %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e.EventBusName != "harness-bus" || e.Source != "dynamodb-harness" || e.DetailType != tableCreated ||
+				e.Detail != `{"table":"MyTable","items":3,"iteration":2}` {
+				t.Errorf("published %+v, want the table event on harness-bus", e)
+			}
+			if errs := h.Stats.TakeWindow().Ops["PutEvents"].Errors; errs != tc.errors {
+				t.Errorf("PutEvents counted %d errors, want %d", errs, tc.errors)
+			}
+		})
+	}
+
+	// A nil publisher, as when -eventbridge-bus is unset, does nothing.
+	var p *LifecyclePublisher
+	p.publish(context.Background(), tableDeleted, tableEvent{Table: "MyTable"})
+}
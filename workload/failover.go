@@ -0,0 +1,123 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// EndpointFailover moves requests to the next -endpoint once the current
+// one has failed to connect -failover-after times in a row, so a run
+// survives a backend node dropping out. A nil failover always uses the
+// first endpoint.
+type EndpointFailover struct {
+	endpoints []*url.URL
+	threshold int
+	logger    *obsv.Logger
+
+	mu       sync.Mutex
+	current  int
+	failures int
+}
+
+// NewEndpointFailover returns a failover across -endpoint.
+func NewEndpointFailover(flags *Config, logger *obsv.Logger) (*EndpointFailover, error) {
+	f := &EndpointFailover{threshold: flags.FailoverAfter, logger: logger}
+	for _, e := range flags.Endpoints {
+		u, err := url.Parse(e)
+		if err != nil {
+			return nil, err
+		}
+		f.endpoints = append(f.endpoints, u)
+	}
+	return f, nil
+}
+
+// Endpoint returns the URL requests currently go to.
+func (f *EndpointFailover) Endpoint() *url.URL {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoints[f.current]
+}
+
+// observe counts err against the current endpoint and fails over when
+// the run of connection errors reaches the threshold. Any other outcome,
+// including API errors, proves the endpoint is reachable.
+func (f *EndpointFailover) observe(err error) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !isConnectionError(err) {
+		f.failures = 0
+		return
+	}
+	f.failures++
+	if f.failures < f.threshold {
+		return
+	}
+	failed := f.endpoints[f.current]
+	f.current = (f.current + 1) % len(f.endpoints)
+	f.failures = 0
+	f.logger.Operationf("Endpoint %s failed to connect %d times in a row; failing over to %s: %v",
+		failed, f.threshold, f.endpoints[f.current], err)
+}
+
+// owns reports whether host is one of the configured endpoints, so
+// requests the SDK routes elsewhere, such as to an access point, are
+// left alone.
+func (f *EndpointFailover) owns(host string) bool {
+	for _, u := range f.endpoints {
+		if u.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware points each attempt at the current endpoint. It runs inside
+// the retry loop, after endpoint resolution and before signing, so a
+// retried request goes to the endpoint failed over to and is signed for
+// it.
+func (f *EndpointFailover) Middleware(stack *middleware.Stack) error {
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("EndpointFailover",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (
+			middleware.FinalizeOutput, middleware.Metadata, error) {
+			req, ok := in.Request.(*smithyhttp.Request)
+			if !ok || !f.owns(req.URL.Host) {
+				return next.HandleFinalize(ctx, in)
+			}
+			u := f.Endpoint()
+			req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+			req.Host = ""
+
+			out, md, err := next.HandleFinalize(ctx, in)
+			f.observe(err)
+			return out, md, err
+		}), "ResolveEndpointV2", middleware.After)
+}
+
+// Describe lists the endpoints in failover order.
+func (f *EndpointFailover) Describe() string {
+	if len(f.endpoints) < 2 {
+		return f.endpoints[0].String()
+	}
+	return fmt.Sprintf("%s (failing over to %d more after %d connection errors)",
+		f.endpoints[0], len(f.endpoints)-1, f.threshold)
+}
+
+// isConnectionError reports whether err means the endpoint could not be
+// reached at all, as opposed to answering with an error.
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
@@ -0,0 +1,68 @@
+package workload
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// closedEndpoint returns the URL of a port nothing listens on.
+func closedEndpoint(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return "http://" + addr
+}
+
+func TestEndpointFailover(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.Write([]byte(`{"TableNames":[]}`))
+	}))
+	defer srv.Close()
+
+	h, sink := newTestHarness(t, closedEndpoint(t)+","+srv.URL, "-failover-after", "2")
+	client := dynamodb.NewFromConfig(h.AWS)
+	// The retryer's third attempt goes to the second endpoint.
+	if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+		t.Fatalf("ListTables after failover: %v", err)
+	}
+	if got := h.endpoint(); got != srv.URL {
+		t.Errorf("endpoint = %s, want %s", got, srv.URL)
+	}
+	if n := sink.Count(obsv.CategoryOperation, "failing over to "+srv.URL); n != 1 {
+		t.Errorf("logged %d failovers, want 1", n)
+	}
+
+	// Once there, API calls that succeed keep the run on it.
+	if _, err := client.ListTables(context.Background(), &dynamodb.ListTablesInput{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.endpoint(); got != srv.URL {
+		t.Errorf("endpoint moved to %s after a successful call", got)
+	}
+}
+
+func TestEndpointFailoverFlags(t *testing.T) {
+	for _, endpoint := range []string{"http://a:4566,", "localhost:4566"} {
+		if _, err := ParseFlags("harness", []string{"-endpoint", endpoint}); err == nil {
+			t.Errorf("-endpoint %q parsed", endpoint)
+		}
+	}
+	flags, err := ParseFlags("harness", []string{"-endpoint", "http://a:4566, http://b:4566"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.Endpoint != "http://a:4566" || len(flags.Endpoints) != 2 {
+		t.Errorf("Endpoint = %s, Endpoints = %v", flags.Endpoint, flags.Endpoints)
+	}
+}
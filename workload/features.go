@@ -0,0 +1,172 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Lou-Varndell/files/obsv"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// Optional features individual workload steps depend on.
+const (
+	featureStreams = "table streams"
+	featurePITR    = "point-in-time recovery"
+)
+
+// localStackHealth is the part of /_localstack/health the harness reads.
+type localStackHealth struct {
+	Edition  string            `json:"edition"`
+	Version  string            `json:"version"`
+	Services map[string]string `json:"services"`
+}
+
+// Features records what the backend can do, so steps it cannot
+// serve are skipped and reported rather than failing the run. A nil
+// value, or one for a backend other than LocalStack, supports everything.
+type Features struct {
+	LocalStack bool
+	Edition    string
+	Version    string
+	services   map[string]string
+
+	mu       sync.Mutex
+	disabled map[string]string
+}
+
+// DetectFeatures reads LocalStack's health endpoint. Any other backend
+// is assumed to offer every service.
+func DetectFeatures(ctx context.Context, h *Harness) (*Features, error) {
+	f := &Features{disabled: make(map[string]string)}
+	status, body, err := localStackGet(ctx, h, "/_localstack/health")
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return f, nil
+	}
+
+	var health localStackHealth
+	if err := json.Unmarshal(body, &health); err != nil {
+		return nil, fmt.Errorf("decode LocalStack health: %w", err)
+	}
+	f.LocalStack = true
+	f.Edition = health.Edition
+	f.Version = health.Version
+	f.services = health.Services
+	if f.Edition == "" {
+		f.Edition = "community"
+	}
+
+	if !f.ServiceAvailable("dynamodbstreams") {
+		f.disable(featureStreams, "dynamodbstreams is "+f.serviceStatus("dynamodbstreams"))
+	}
+	return f, nil
+}
+
+func (f *Features) serviceStatus(name string) string {
+	if status, ok := f.services[name]; ok {
+		return status
+	}
+	return "not enabled"
+}
+
+// ServiceAvailable reports whether LocalStack serves name.
+func (f *Features) ServiceAvailable(name string) bool {
+	if f == nil || !f.LocalStack {
+		return true
+	}
+	switch f.serviceStatus(name) {
+	case "available", "running":
+		return true
+	default:
+		return false
+	}
+}
+
+// Supports reports whether feature may be used.
+func (f *Features) Supports(feature string) bool {
+	if f == nil {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, off := f.disabled[feature]
+	return !off
+}
+
+// disable turns feature off for the rest of the run and reports whether
+// it was on until now.
+func (f *Features) disable(feature, reason string) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, off := f.disabled[feature]; off {
+		return false
+	}
+	f.disabled[feature] = reason
+	return true
+}
+
+// SkipUnavailable drops the workloads whose services the backend does
+// not serve, logging each one skipped.
+func (f *Features) SkipUnavailable(workloads []Workload, logger *obsv.Logger) []Workload {
+	var kept []Workload
+	for _, w := range workloads {
+		var missing []string
+		for _, service := range ModuleServices[w.Name()] {
+			if !f.ServiceAvailable(service) {
+				missing = append(missing, service+" is "+f.serviceStatus(service))
+			}
+		}
+		if len(missing) > 0 {
+			logger.Operationf("Skipping module %s: %s", w.Name(), strings.Join(missing, ", "))
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return kept
+}
+
+// Describe summarises the backend and any features turned off.
+func (f *Features) Describe() string {
+	if f == nil || !f.LocalStack {
+		return "not LocalStack; assuming every service is available"
+	}
+	desc := fmt.Sprintf("LocalStack %s %s", f.Edition, f.Version)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	features := make([]string, 0, len(f.disabled))
+	for feature, reason := range f.disabled {
+		features = append(features, feature+" ("+reason+")")
+	}
+	sort.Strings(features)
+	if len(features) > 0 {
+		desc += "; unavailable: " + strings.Join(features, ", ")
+	}
+	return desc
+}
+
+// isNotImplemented reports whether err says the backend lacks the
+// operation, as LocalStack does for calls outside its edition.
+func isNotImplemented(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotImplemented", "NotImplementedException", "InternalFailure":
+			return true
+		}
+	}
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotImplemented
+}
@@ -0,0 +1,58 @@
+package workload
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectFeatures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_localstack/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"edition":"community","version":"3.8.1",
+			"services":{"dynamodb":"running","dynamodbstreams":"disabled","sqs":"available"}}`))
+	}))
+	defer srv.Close()
+
+	h, _ := newTestHarness(t, srv.URL, "-modules", "dynamodb,kms,sqs", "-quiet")
+	f, err := DetectFeatures(context.Background(), h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.LocalStack || f.Version != "3.8.1" {
+		t.Errorf("detected %+v, want LocalStack 3.8.1", f)
+	}
+	if f.Supports(featureStreams) {
+		t.Error("streams reported as supported with dynamodbstreams disabled")
+	}
+
+	workloads, err := NewWorkloads(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, w := range f.SkipUnavailable(workloads, h.Logger) {
+		names = append(names, w.Name())
+	}
+	if len(names) != 2 || names[0] != "dynamodb" || names[1] != "sqs" {
+		t.Errorf("kept modules %v, want [dynamodb sqs]", names)
+	}
+}
+
+func TestDetectFeaturesOtherBackend(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	h, _ := newTestHarness(t, srv.URL, "-quiet")
+	f, err := DetectFeatures(context.Background(), h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.LocalStack || !f.ServiceAvailable("kms") || !f.Supports(featureStreams) {
+		t.Errorf("a non-LocalStack backend should support everything, got %+v", f)
+	}
+}
@@ -0,0 +1,13 @@
+package workload
+
+import (
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+)
+
+// newTestHarness builds a harness for endpoint from args.
+func newTestHarness(t *testing.T, endpoint string, args ...string) (*Harness, *ddbtest.RecordingSink) {
+	t.Helper()
+	return NewTestHarness(t, endpoint, nil, args...)
+}
@@ -0,0 +1,88 @@
+//go:build integration
+
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestTableLifecycle(t *testing.T) {
+	endpoint := ddbtest.NewLocalStack(t)
+	h, _ := newTestHarness(t, endpoint, "-items", "3", "-table-per-iteration", "-delete-tables")
+	ctx := context.Background()
+	w := &dynamoWorkload{Harness: h, client: dynamodb.NewFromConfig(h.AWS)}
+
+	if err := w.RunIteration(ctx); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	cp := h.Checkpoint
+	if cp.Phase != phaseDone || cp.Written != 3 || cp.Verified != 3 {
+		t.Fatalf("checkpoint %+v, want phase done with 3 written and verified", cp)
+	}
+
+	_, err := w.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &cp.Table})
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		t.Errorf("table %s still exists after -delete-tables: %v", cp.Table, err)
+	}
+}
+
+func TestTableLifecycleResume(t *testing.T) {
+	endpoint := ddbtest.NewLocalStack(t)
+	h, _ := newTestHarness(t, endpoint, "-items", "4")
+	ctx := context.Background()
+	w := &dynamoWorkload{Harness: h, client: dynamodb.NewFromConfig(h.AWS)}
+
+	// Stop after seeding two items, as an interrupted run would.
+	cp := h.Checkpoint
+	cp.Phase = phaseCreate
+	table := h.Flags.TablePrefix + "Resume"
+	if err := w.createTable(ctx, table); err != nil {
+		t.Fatal(err)
+	}
+	cp.startTable(table)
+	for n := 0; n < 2; n++ {
+		if err := w.putItem(ctx, table, n); err != nil {
+			t.Fatal(err)
+		}
+		cp.Written++
+	}
+
+	if err := w.RunIteration(ctx); err != nil {
+		t.Fatalf("resumed iteration: %v", err)
+	}
+	if cp.Table != table || cp.Written != 4 || cp.Verified != 4 {
+		t.Fatalf("checkpoint %+v, want %s with 4 written and verified", cp, table)
+	}
+}
+
+func TestWorkloadEngine(t *testing.T) {
+	endpoint := ddbtest.NewLocalStack(t)
+	h, _ := newTestHarness(t, endpoint,
+		"-modules", "dynamodb,s3,sqs,sns,kinesis,kms",
+		"-iterations", "2", "-interval", "0",
+		"-sqs-messages", "5", "-sqs-wait", "1s", "-kinesis-records", "10")
+
+	workloads, err := NewWorkloads(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunLoop(context.Background(), h, workloads); err != nil {
+		t.Fatalf("run loop: %v", err)
+	}
+	if h.Checkpoint.Iteration != 2 {
+		t.Errorf("completed %d iterations, want 2", h.Checkpoint.Iteration)
+	}
+	ops := h.Stats.TakeWindow().Ops
+	for _, op := range []string{"CreateTable", "UploadPart", "SendMessageBatch", "Publish", "PutRecords", "GenerateDataKey"} {
+		if _, ok := ops[op]; !ok {
+			t.Errorf("no %s calls recorded", op)
+		}
+	}
+}
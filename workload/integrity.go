@@ -0,0 +1,176 @@
+package workload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// checksumAttribute holds an item's content hash under -integrity.
+const checksumAttribute = "Checksum"
+
+// maxBatchGet is the most keys one BatchGetItem call may read.
+const maxBatchGet = 100
+
+// ChecksumError is the cause of a mismatch where a read item no longer
+// hashes to the checksum it was written with.
+type ChecksumError struct {
+	Stored   string
+	Computed string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("content hashes to %s but was written as %s", e.Computed, e.Stored)
+}
+
+// itemChecksum hashes every attribute of item but its checksum. It
+// covers string, number and binary values, which are all a seeded item
+// holds; other attributes count by name and type only.
+func itemChecksum(item map[string]types.AttributeValue) string {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		if name != checksumAttribute {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00", name, attributeType(item[name]))
+		switch v := item[name].(type) {
+		case *types.AttributeValueMemberS:
+			h.Write([]byte(v.Value))
+		case *types.AttributeValueMemberN:
+			h.Write([]byte(v.Value))
+		case *types.AttributeValueMemberB:
+			h.Write(v.Value)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// checkChecksum returns the cause of a mismatch unless item still
+// hashes to its stored checksum.
+func checkChecksum(item map[string]types.AttributeValue) error {
+	stored, err := stringAttribute(item, checksumAttribute)
+	if err != nil {
+		return err
+	}
+	if computed := itemChecksum(item); computed != stored {
+		return &ChecksumError{Stored: stored, Computed: computed}
+	}
+	return nil
+}
+
+// tolerate counts a damaged item, one missing or failing its checks,
+// under -integrity and lets verification carry on while the run's
+// damaged items stay within -integrity-max-damaged. Other errors,
+// -strict divergences, and every error without -integrity are returned
+// as they are.
+func (w *dynamoWorkload) tolerate(err error) error {
+	var he *obsv.HarnessError
+	var de *DivergenceError
+	if err == nil || !w.Flags.Integrity || !errors.As(err, &he) || he.Class != obsv.ClassMismatch || errors.As(err, &de) {
+		return err
+	}
+	missing := errors.Is(err, ErrItemNotFound)
+	if missing {
+		w.missing++
+	} else {
+		w.corrupt++
+	}
+	w.Stats.ItemDamaged(missing)
+	w.Logger.Operationf("Integrity: %v", err)
+	if w.corrupt+w.missing > w.Flags.IntegrityMaxDamaged {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    he.Op,
+			Err: fmt.Errorf("%d corrupt and %d missing items exceed -integrity-max-damaged %d, last: %w",
+				w.corrupt, w.missing, w.Flags.IntegrityMaxDamaged, err),
+		}
+	}
+	return nil
+}
+
+// verifyBatch reads the seeded items back with BatchGetItem, up to 100
+// at a time, checkpointing as it goes.
+func (w *dynamoWorkload) verifyBatch(ctx context.Context) error {
+	cp := w.Checkpoint
+	for cp.Verified < cp.Written {
+		first, count := cp.Verified, min(maxBatchGet, cp.Written-cp.Verified)
+		items, err := w.batchGetItems(ctx, cp.Table, first, count)
+		if err != nil {
+			return err
+		}
+		for n := first; n < first+count; n++ {
+			if err := w.tolerate(w.checkItem("BatchGetItem", n, items[itemKey(n)])); err != nil {
+				return err
+			}
+		}
+		before := cp.Verified
+		cp.Verified += count
+		if cp.Verified/checkpointEvery != before/checkpointEvery {
+			if err := w.saveCheckpoint(); err != nil {
+				return err
+			}
+		}
+	}
+	w.Logger.Operationf("Batch read %d items from table %s", cp.Written, cp.Table)
+	return nil
+}
+
+// batchGetItems reads count items numbered from first, rereading any
+// keys the table leaves unprocessed, and returns them by key.
+func (w *dynamoWorkload) batchGetItems(ctx context.Context, tableName string, first, count int) (map[string]map[string]types.AttributeValue, error) {
+	keys := make([]map[string]types.AttributeValue, count)
+	for i := range keys {
+		keys[i] = map[string]types.AttributeValue{w.Flags.HashKey: &types.AttributeValueMemberS{Value: itemKey(first + i)}}
+	}
+	items := make(map[string]map[string]types.AttributeValue, count)
+	backoff := 50 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		var out *dynamodb.BatchGetItemOutput
+		err := obsv.Timed(ctx, w.Stats, "BatchGetItem", w.Flags.DataTimeout, func(ctx context.Context) error {
+			var err error
+			out, err = w.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{tableName: {Keys: keys}},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Responses[tableName] {
+			if id, err := stringAttribute(item, w.Flags.HashKey); err == nil {
+				items[id] = item
+			}
+		}
+		keys = out.UnprocessedKeys[tableName].Keys
+		if len(keys) == 0 {
+			return items, nil
+		}
+		if attempt == unprocessedAttempts {
+			return nil, &obsv.HarnessError{
+				Class: obsv.ClassThrottling,
+				Op:    "BatchGetItem",
+				Err:   fmt.Errorf("%d of %d keys still unprocessed after %d attempts", len(keys), count, attempt),
+			}
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
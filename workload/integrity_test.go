@@ -0,0 +1,128 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestItemChecksum(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"ID":   &types.AttributeValueMemberS{Value: "1"},
+		"Name": &types.AttributeValueMemberS{Value: "LocalUser"},
+	}
+	sum := itemChecksum(item)
+	item[checksumAttribute] = &types.AttributeValueMemberS{Value: sum}
+	if err := checkChecksum(item); err != nil {
+		t.Fatalf("intact item: %v", err)
+	}
+
+	// The hash key counts too, so an item moved under another key fails.
+	item["ID"] = &types.AttributeValueMemberS{Value: "2"}
+	var ce *ChecksumError
+	if err := checkChecksum(item); !errors.As(err, &ce) || ce.Stored != sum {
+		t.Errorf("item under another key: got %v, want a ChecksumError", err)
+	}
+	delete(item, checksumAttribute)
+	var ae *AttributeError
+	if err := checkChecksum(item); !errors.As(err, &ae) || ae.Name != checksumAttribute {
+		t.Errorf("item without a checksum: got %v, want an AttributeError for %s", err, checksumAttribute)
+	}
+}
+
+func TestIntegrityVerifyModes(t *testing.T) {
+	for _, mode := range verifyModes {
+		t.Run(mode, func(t *testing.T) {
+			h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "150",
+				"-integrity", "-verify-mode", mode, "-quiet")
+			w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+			if err := w.RunIteration(context.Background()); err != nil {
+				t.Fatalf("iteration: %v", err)
+			}
+			if h.Checkpoint.Verified != 150 || w.corrupt+w.missing != 0 {
+				t.Errorf("verified %d items with %d corrupt and %d missing, want 150 intact",
+					h.Checkpoint.Verified, w.corrupt, w.missing)
+			}
+			if mode == "batch" {
+				if n := h.Stats.TakeWindow().Ops["BatchGetItem"].Count; n != 2 {
+					t.Errorf("%d BatchGetItem calls for 150 items, want 2", n)
+				}
+			}
+		})
+	}
+}
+
+// damageItems seeds a table under h, then tampers with one item behind
+// the checksum's back and deletes another.
+func damageItems(t *testing.T, w *dynamoWorkload) {
+	t.Helper()
+	ctx := context.Background()
+	if err := w.RunIteration(ctx); err != nil {
+		t.Fatalf("seeding iteration: %v", err)
+	}
+	cp := w.Checkpoint
+	tampered := w.seededItem(3)
+	tampered["Extra"] = &types.AttributeValueMemberS{Value: "bit rot"}
+	if _, err := w.Memory.PutItem(ctx, &dynamodb.PutItemInput{TableName: &cp.Table, Item: tampered}); err != nil {
+		t.Fatal(err)
+	}
+	key := map[string]types.AttributeValue{w.Flags.HashKey: &types.AttributeValueMemberS{Value: itemKey(5)}}
+	if _, err := w.Memory.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: map[string][]types.WriteRequest{
+		cp.Table: {{DeleteRequest: &types.DeleteRequest{Key: key}}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	cp.Phase, cp.Verified = phaseVerify, 0
+}
+
+func TestIntegrityCountsDamage(t *testing.T) {
+	for _, mode := range verifyModes {
+		t.Run(mode, func(t *testing.T) {
+			h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "10",
+				"-integrity", "-integrity-max-damaged", "2", "-verify-mode", mode, "-quiet")
+			w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+			damageItems(t, w)
+			if err := w.RunIteration(context.Background()); err != nil {
+				t.Fatalf("iteration with damage within -integrity-max-damaged: %v", err)
+			}
+			if w.corrupt != 1 || w.missing != 1 || h.Checkpoint.Verified != 10 {
+				t.Errorf("%d corrupt and %d missing of %d verified, want 1 and 1 of 10",
+					w.corrupt, w.missing, h.Checkpoint.Verified)
+			}
+		})
+	}
+}
+
+func TestIntegrityFailsPastThreshold(t *testing.T) {
+	for _, mode := range verifyModes {
+		t.Run(mode, func(t *testing.T) {
+			h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "10",
+				"-integrity", "-integrity-max-damaged", "1", "-verify-mode", mode, "-quiet")
+			w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+			damageItems(t, w)
+			err := w.RunIteration(context.Background())
+			var he *obsv.HarnessError
+			if !errors.As(err, &he) || he.Class != obsv.ClassMismatch {
+				t.Fatalf("got %v, want a mismatch once damage exceeds -integrity-max-damaged", err)
+			}
+			if !errors.Is(err, ErrItemNotFound) {
+				t.Errorf("%v does not wrap the missing item that crossed the threshold", err)
+			}
+		})
+	}
+}
+
+func TestWithoutIntegrityFirstDamageFails(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "10", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+	damageItems(t, w)
+	err := w.RunIteration(context.Background())
+	if !errors.Is(err, ErrItemNotFound) || w.missing != 0 {
+		t.Errorf("got %v with %d counted missing, want the deleted item to fail the iteration uncounted", err, w.missing)
+	}
+}
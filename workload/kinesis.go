@@ -0,0 +1,212 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// kinesisWorkload writes records across a set of partition keys and reads
+// every shard back, measuring how long records take to reach the stream.
+type kinesisWorkload struct {
+	*Harness
+	client *kinesis.Client
+}
+
+func (w *kinesisWorkload) Name() string { return "kinesis" }
+
+// RunIteration exercises one stream lifecycle.
+func (w *kinesisWorkload) RunIteration(ctx context.Context) error {
+	streamName := w.resourceName("MyStream")
+	if err := w.createStream(ctx, streamName); err != nil {
+		return err
+	}
+
+	written := make(map[string]bool, w.Flags.KinesisRecords)
+	for n := 0; n < w.Flags.KinesisRecords; n += 500 {
+		ids, err := w.putRecords(ctx, streamName, n, min(500, w.Flags.KinesisRecords-n))
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			written[id] = true
+		}
+	}
+	w.Logger.Operationf("Put %d records to %s across %d partition keys", len(written), streamName, w.Flags.KinesisPartitionKeys)
+
+	if err := w.readShards(ctx, streamName, written); err != nil {
+		return err
+	}
+
+	err := obsv.Timed(ctx, w.Stats, "DeleteStream", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.DeleteStream(ctx, &kinesis.DeleteStreamInput{
+			StreamName:              &streamName,
+			EnforceConsumerDeletion: aws.Bool(true),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Stream deleted: %s", streamName)
+	return nil
+}
+
+// createStream creates the stream and waits for it to become active.
+func (w *kinesisWorkload) createStream(ctx context.Context, streamName string) error {
+	err := obsv.Timed(ctx, w.Stats, "CreateStream", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.CreateStream(ctx, &kinesis.CreateStreamInput{
+			StreamName: &streamName,
+			ShardCount: aws.Int32(int32(w.Flags.KinesisShards)),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = obsv.Timed(ctx, w.Stats, "WaitStreamActive", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		waiter := kinesis.NewStreamExistsWaiter(w.client, func(o *kinesis.StreamExistsWaiterOptions) {
+			o.MinDelay = 200 * time.Millisecond
+			o.MaxDelay = 2 * time.Second
+		})
+		return waiter.Wait(ctx, &kinesis.DescribeStreamInput{StreamName: &streamName}, obsv.WaitTimeout(ctx, w.Flags.ControlTimeout))
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Stream created: %s (%d shards)", streamName, w.Flags.KinesisShards)
+	return nil
+}
+
+// putRecords writes count records numbered from first, spreading them
+// round-robin over the partition keys, and returns their record IDs.
+func (w *kinesisWorkload) putRecords(ctx context.Context, streamName string, first, count int) ([]string, error) {
+	entries := make([]types.PutRecordsRequestEntry, count)
+	for i := range entries {
+		n := first + i
+		entries[i] = types.PutRecordsRequestEntry{
+			PartitionKey: aws.String(fmt.Sprintf("key-%d", n%w.Flags.KinesisPartitionKeys)),
+			Data:         []byte(strconv.Itoa(n) + "|" + strconv.FormatInt(time.Now().UnixNano(), 10)),
+		}
+	}
+
+	var out *kinesis.PutRecordsOutput
+	err := obsv.Timed(ctx, w.Stats, "PutRecords", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.PutRecords(ctx, &kinesis.PutRecordsInput{
+			StreamName: &streamName,
+			Records:    entries,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if failed := aws.ToInt32(out.FailedRecordCount); failed > 0 {
+		return nil, obsv.Classify("PutRecords", fmt.Errorf("%d of %d records failed", failed, count))
+	}
+
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = strconv.Itoa(first + i)
+	}
+	return ids, nil
+}
+
+// recordPropagation returns the ID of a record written by putRecords and
+// how long it took from being sent to arriving in the stream. The
+// stream's arrival timestamp is used rather than the time of reading,
+// which shards read one after another would inflate. ok is false when
+// the record carries no send time or arrival timestamp.
+func recordPropagation(r types.Record) (id string, delay time.Duration, ok bool) {
+	id, sentAt, found := strings.Cut(string(r.Data), "|")
+	ns, err := strconv.ParseInt(sentAt, 10, 64)
+	if !found || err != nil || r.ApproximateArrivalTimestamp == nil {
+		return id, 0, false
+	}
+	// Arrival is stamped by the server's clock, to the millisecond.
+	return id, max(r.ApproximateArrivalTimestamp.Sub(time.Unix(0, ns)), 0), true
+}
+
+// readShards reads every shard from the start until all written records
+// have been seen or the shards stop returning data.
+func (w *kinesisWorkload) readShards(ctx context.Context, streamName string, written map[string]bool) error {
+	var shards *kinesis.ListShardsOutput
+	err := obsv.Timed(ctx, w.Stats, "ListShards", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		shards, err = w.client.ListShards(ctx, &kinesis.ListShardsInput{StreamName: &streamName})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	remaining := len(written)
+	for _, shard := range shards.Shards {
+		var it *kinesis.GetShardIteratorOutput
+		err := obsv.Timed(ctx, w.Stats, "GetShardIterator", w.Flags.DataTimeout, func(ctx context.Context) error {
+			var err error
+			it, err = w.client.GetShardIterator(ctx, &kinesis.GetShardIteratorInput{
+				StreamName:        &streamName,
+				ShardId:           shard.ShardId,
+				ShardIteratorType: types.ShardIteratorTypeTrimHorizon,
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		seen := 0
+		for iterator, empty := it.ShardIterator, 0; iterator != nil && empty < 3 && remaining > 0; {
+			var out *kinesis.GetRecordsOutput
+			err := obsv.Timed(ctx, w.Stats, "GetRecords", w.Flags.DataTimeout, func(ctx context.Context) error {
+				var err error
+				out, err = w.client.GetRecords(ctx, &kinesis.GetRecordsInput{ShardIterator: iterator})
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, r := range out.Records {
+				id, delay, ok := recordPropagation(r)
+				if !written[id] {
+					continue
+				}
+				if ok {
+					w.Stats.Record("KinesisPropagation", delay, nil)
+				}
+				delete(written, id)
+				remaining--
+				seen++
+			}
+
+			if len(out.Records) == 0 {
+				empty++
+				time.Sleep(200 * time.Millisecond)
+			} else {
+				empty = 0
+			}
+			iterator = out.NextShardIterator
+		}
+		w.Logger.Operationf("Read %d records from %s", seen, aws.ToString(shard.ShardId))
+	}
+
+	if remaining > 0 {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "GetRecords",
+			Err:   fmt.Errorf("%s: %d records were written but never read", streamName, remaining),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package workload
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+func TestRecordPropagation(t *testing.T) {
+	sent := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := func(s string) []byte { return []byte(s) }
+	stamped := "7|" + strconv.FormatInt(sent.UnixNano(), 10)
+
+	for _, tc := range []struct {
+		name   string
+		record types.Record
+		id     string
+		delay  time.Duration
+		ok     bool
+	}{
+		{"arrived", types.Record{Data: data(stamped), ApproximateArrivalTimestamp: aws.Time(sent.Add(40 * time.Millisecond))}, "7", 40 * time.Millisecond, true},
+		{"server clock behind", types.Record{Data: data(stamped), ApproximateArrivalTimestamp: aws.Time(sent.Add(-time.Second))}, "7", 0, true},
+		{"no arrival timestamp", types.Record{Data: data(stamped)}, "7", 0, false},
+		{"no send time", types.Record{Data: data("7"), ApproximateArrivalTimestamp: aws.Time(sent)}, "7", 0, false},
+		{"bad send time", types.Record{Data: data("7|soon"), ApproximateArrivalTimestamp: aws.Time(sent)}, "7", 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			id, delay, ok := recordPropagation(tc.record)
+			if id != tc.id || delay != tc.delay || ok != tc.ok {
+				t.Errorf("recordPropagation = %q, %s, %t, want %q, %s, %t", id, delay, ok, tc.id, tc.delay, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// kmsWorkload envelope-encrypts item payloads with a KMS data key before
+// PutItem and decrypts them after GetItem, so the stats show what
+// client-side encryption adds on top of the DynamoDB calls.
+type kmsWorkload struct {
+	*Harness
+	client *kms.Client
+	tables *dynamoWorkload
+}
+
+func (w *kmsWorkload) Name() string { return "kms" }
+
+// RunIteration creates a key and a table named under -table-prefix,
+// round-trips encrypted items through the table, deletes it for
+// -delete-tables and schedules the key for deletion.
+func (w *kmsWorkload) RunIteration(ctx context.Context) error {
+	tableName, err := w.tables.createNamedTable(ctx, w.Flags.TablePrefix+"Encrypted")
+	if err != nil {
+		return err
+	}
+	keyID, err := w.createKey(ctx, "harness envelope key for "+tableName)
+	if err != nil {
+		return err
+	}
+
+	for n := 0; n < w.Flags.Items; n++ {
+		payload := []byte(fmt.Sprintf("secret payload %d", n))
+		if err := w.putEncrypted(ctx, keyID, tableName, n, payload); err != nil {
+			return err
+		}
+		if err := w.getEncrypted(ctx, tableName, n, payload); err != nil {
+			return err
+		}
+	}
+	w.Logger.Operationf("Round-tripped %d encrypted items through %s", w.Flags.Items, tableName)
+	if w.Flags.DeleteTables {
+		if err := w.tables.deleteTable(ctx, tableName); err != nil {
+			return err
+		}
+	}
+
+	err = obsv.Timed(ctx, w.Stats, "ScheduleKeyDeletion", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.ScheduleKeyDeletion(ctx, &kms.ScheduleKeyDeletionInput{
+			KeyId:               &keyID,
+			PendingWindowInDays: aws.Int32(7),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Key scheduled for deletion: %s", keyID)
+	return nil
+}
+
+func (w *kmsWorkload) createKey(ctx context.Context, description string) (string, error) {
+	var out *kms.CreateKeyOutput
+	err := obsv.Timed(ctx, w.Stats, "CreateKey", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.CreateKey(ctx, &kms.CreateKeyInput{Description: &description})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	keyID := aws.ToString(out.KeyMetadata.KeyId)
+	w.Logger.Operationf("Key created: %s", keyID)
+	return keyID, nil
+}
+
+// putEncrypted seals payload under a fresh data key and stores the
+// ciphertext alongside the KMS-encrypted data key.
+func (w *kmsWorkload) putEncrypted(ctx context.Context, keyID, tableName string, n int, payload []byte) error {
+	start := time.Now()
+	var dataKey *kms.GenerateDataKeyOutput
+	err := obsv.Timed(ctx, w.Stats, "GenerateDataKey", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		dataKey, err = w.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+			KeyId:   &keyID,
+			KeySpec: kmstypes.DataKeySpecAes256,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sealed, err := sealPayload(dataKey.Plaintext, payload)
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "EnvelopeSeal", Err: err}
+	}
+	w.Stats.Record("EnvelopeSeal", time.Since(start), nil)
+
+	return obsv.Timed(ctx, w.Stats, "PutItem", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.tables.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: &tableName,
+			Item: map[string]ddbtypes.AttributeValue{
+				w.Flags.HashKey: &ddbtypes.AttributeValueMemberS{Value: itemKey(n)},
+				"Payload":       &ddbtypes.AttributeValueMemberB{Value: sealed},
+				"DataKey":       &ddbtypes.AttributeValueMemberB{Value: dataKey.CiphertextBlob},
+			},
+		})
+		return err
+	})
+}
+
+// getEncrypted reads the item back, decrypts its data key with KMS and
+// checks the opened payload against what was written.
+func (w *kmsWorkload) getEncrypted(ctx context.Context, tableName string, n int, want []byte) error {
+	id := itemKey(n)
+	var resp *dynamodb.GetItemOutput
+	err := obsv.Timed(ctx, w.Stats, "GetItem", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		resp, err = w.tables.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: &tableName,
+			Key: map[string]ddbtypes.AttributeValue{
+				w.Flags.HashKey: &ddbtypes.AttributeValueMemberS{Value: id},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.Item == nil {
+		err = ErrItemNotFound
+	}
+	var sealed, encryptedKey []byte
+	if err == nil {
+		sealed, err = binaryAttribute(resp.Item, "Payload")
+	}
+	if err == nil {
+		encryptedKey, err = binaryAttribute(resp.Item, "DataKey")
+	}
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassMismatch, Op: "GetItem", Err: fmt.Errorf("item %s: %w", id, err)}
+	}
+
+	start := time.Now()
+	var dataKey *kms.DecryptOutput
+	err = obsv.Timed(ctx, w.Stats, "Decrypt", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		dataKey, err = w.client.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: encryptedKey})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	got, err := openPayload(dataKey.Plaintext, sealed)
+	if err != nil {
+		return &obsv.HarnessError{Class: obsv.ClassMismatch, Op: "EnvelopeOpen", Err: fmt.Errorf("item %s: %w", id, err)}
+	}
+	w.Stats.Record("EnvelopeOpen", time.Since(start), nil)
+
+	if !bytes.Equal(got, want) {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "EnvelopeOpen",
+			Err:   fmt.Errorf("item %s: decrypted payload %q, want %q", id, got, want),
+		}
+	}
+	return nil
+}
+
+// sealPayload encrypts plaintext with AES-GCM under key, prefixing the
+// random nonce to the ciphertext.
+func sealPayload(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openPayload reverses sealPayload.
+func openPayload(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed payload is %d bytes, shorter than the nonce", len(sealed))
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,110 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// fakeKMS answers the KMS calls an envelope round trip makes. Its data
+// keys are their own ciphertext, so Decrypt hands back what it is sent.
+func fakeKMS(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ CiphertextBlob []byte }
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("decode %s: %v", r.Header.Get("X-Amz-Target"), err)
+		}
+		var out any
+		switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "TrentService.") {
+		case "CreateKey":
+			out = map[string]any{"KeyMetadata": map[string]string{"KeyId": "key-1"}}
+		case "GenerateDataKey":
+			key := bytes.Repeat([]byte{7}, 32)
+			out = map[string]any{"KeyId": "key-1", "Plaintext": key, "CiphertextBlob": key}
+		case "Decrypt":
+			out = map[string]any{"KeyId": "key-1", "Plaintext": in.CiphertextBlob}
+		default:
+			out = map[string]any{}
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		json.NewEncoder(w).Encode(out)
+	}))
+}
+
+func TestKMSRunIteration(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		tables int
+	}{
+		{"keep tables", nil, 1},
+		{"delete tables", []string{"-delete-tables"}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := fakeKMS(t)
+			defer srv.Close()
+			h, _ := newTestHarness(t, srv.URL, append([]string{"-items", "3", "-quiet"}, tc.args...)...)
+			memory := ddbtest.NewMemory()
+			w := &kmsWorkload{Harness: h, client: kms.NewFromConfig(h.AWS), tables: &dynamoWorkload{Harness: h, client: memory}}
+			if err := w.RunIteration(context.Background()); err != nil {
+				t.Fatalf("RunIteration: %v", err)
+			}
+
+			out, err := memory.ListTables(context.Background(), &dynamodb.ListTablesInput{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.TableNames) != tc.tables {
+				t.Fatalf("tables left %v, want %d", out.TableNames, tc.tables)
+			}
+			for _, name := range out.TableNames {
+				if !strings.HasPrefix(name, h.Flags.TablePrefix) {
+					t.Errorf("table %s is not under -table-prefix %s", name, h.Flags.TablePrefix)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvelopePayload(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	sealed, err := sealPayload(key, []byte("secret payload 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	for _, tc := range []struct {
+		name   string
+		key    []byte
+		sealed []byte
+		want   string
+	}{
+		{name: "round trip", key: key, sealed: sealed, want: "secret payload 1"},
+		{name: "wrong key", key: bytes.Repeat([]byte{2}, 32), sealed: sealed},
+		{name: "tampered", key: key, sealed: tampered},
+		{name: "truncated", key: key, sealed: sealed[:4]},
+		{name: "bad key size", key: key[:7], sealed: sealed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := openPayload(tc.key, tc.sealed)
+			if tc.want == "" && err == nil || tc.want != "" && (err != nil || string(got) != tc.want) {
+				t.Errorf("openPayload = %q, %v, want %q", got, err, tc.want)
+			}
+		})
+	}
+
+	again, err := sealPayload(key, []byte("secret payload 1"))
+	if err != nil || bytes.Equal(again, sealed) {
+		t.Errorf("sealing twice gave the same ciphertext, so the nonce is not random")
+	}
+}
@@ -0,0 +1,125 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// lambdaEvent is the generated payload sent to the function.
+type lambdaEvent struct {
+	ID     string `json:"id"`
+	Mode   string `json:"mode"`
+	SentAt int64  `json:"sentAt"`
+}
+
+// lambdaWorkload invokes an existing function synchronously and
+// asynchronously and checks what comes back.
+type lambdaWorkload struct {
+	*Harness
+	client *lambda.Client
+}
+
+func (w *lambdaWorkload) Name() string { return "lambda" }
+
+// RunIteration sends -lambda-invocations events each way.
+func (w *lambdaWorkload) RunIteration(ctx context.Context) error {
+	suffix := time.Now().Format("150405")
+	for n := 0; n < w.Flags.LambdaInvocations; n++ {
+		id := fmt.Sprintf("%s-%d", suffix, n)
+		if err := w.invokeSync(ctx, id); err != nil {
+			return err
+		}
+		if err := w.invokeAsync(ctx, id); err != nil {
+			return err
+		}
+	}
+	w.Logger.Operationf("Invoked %s %d times synchronously and %d times asynchronously",
+		w.Flags.LambdaFunction, w.Flags.LambdaInvocations, w.Flags.LambdaInvocations)
+	return nil
+}
+
+// invokeSync waits for the function's result. It must not report a
+// function error and must return JSON; with -lambda-echo the result must
+// carry the event's id back.
+func (w *lambdaWorkload) invokeSync(ctx context.Context, id string) error {
+	payload, err := json.Marshal(lambdaEvent{ID: id, Mode: "sync", SentAt: time.Now().UnixNano()})
+	if err != nil {
+		return err
+	}
+
+	var out *lambda.InvokeOutput
+	err = obsv.Timed(ctx, w.Stats, "Invoke", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName:   &w.Flags.LambdaFunction,
+			InvocationType: types.InvocationTypeRequestResponse,
+			Payload:        payload,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if out.FunctionError != nil {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "Invoke",
+			Err:   fmt.Errorf("event %s: function error %s: %s", id, aws.ToString(out.FunctionError), out.Payload),
+		}
+	}
+	var result lambdaEvent
+	if err := json.Unmarshal(out.Payload, &result); err != nil {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "Invoke",
+			Err:   fmt.Errorf("event %s: response is not JSON: %w", id, err),
+		}
+	}
+	if w.Flags.LambdaEcho && result.ID != id {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "Invoke",
+			Err:   fmt.Errorf("event %s: expected echoed id, got %q", id, result.ID),
+		}
+	}
+	return nil
+}
+
+// invokeAsync queues the event; the service only acknowledges it.
+func (w *lambdaWorkload) invokeAsync(ctx context.Context, id string) error {
+	payload, err := json.Marshal(lambdaEvent{ID: id, Mode: "async", SentAt: time.Now().UnixNano()})
+	if err != nil {
+		return err
+	}
+
+	var out *lambda.InvokeOutput
+	err = obsv.Timed(ctx, w.Stats, "InvokeAsync", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName:   &w.Flags.LambdaFunction,
+			InvocationType: types.InvocationTypeEvent,
+			Payload:        payload,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if out.StatusCode != http.StatusAccepted {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "InvokeAsync",
+			Err:   fmt.Errorf("event %s: expected status %d, got %d", id, http.StatusAccepted, out.StatusCode),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+func TestLambdaRunIteration(t *testing.T) {
+	echo := func(event []byte) (string, []byte) { return "", event }
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		sync   func(event []byte) (functionError string, result []byte)
+		status int
+		err    string
+	}{
+		{name: "echo", args: []string{"-lambda-echo"}, sync: echo, status: http.StatusAccepted},
+		{name: "any json", sync: func([]byte) (string, []byte) { return "", []byte(`{"ok":true}`) }, status: http.StatusAccepted},
+		{name: "function error", sync: func([]byte) (string, []byte) {
+			return "Unhandled", []byte(`{"errorMessage":"boom"}`)
+		}, status: http.StatusAccepted, err: "function error Unhandled"},
+		{name: "not json", sync: func([]byte) (string, []byte) { return "", []byte("done") }, status: http.StatusAccepted, err: "not JSON"},
+		{name: "id not echoed", args: []string{"-lambda-echo"}, sync: func([]byte) (string, []byte) {
+			return "", []byte(`{"id":"other"}`)
+		}, status: http.StatusAccepted, err: "expected echoed id"},
+		{name: "async not accepted", sync: echo, status: http.StatusOK, err: "expected status 202"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				event, _ := io.ReadAll(r.Body)
+				if r.Header.Get("X-Amz-Invocation-Type") == "Event" {
+					w.WriteHeader(tc.status)
+					return
+				}
+				functionError, result := tc.sync(event)
+				if functionError != "" {
+					w.Header().Set("X-Amz-Function-Error", functionError)
+				}
+				w.Write(result)
+			}))
+			defer srv.Close()
+
+			h, _ := newTestHarness(t, srv.URL, append([]string{"-lambda-function", "echo", "-lambda-invocations", "2", "-quiet"}, tc.args...)...)
+			w := &lambdaWorkload{Harness: h, client: lambda.NewFromConfig(h.AWS)}
+			err := w.RunIteration(context.Background())
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("RunIteration: %v", err)
+				}
+				return
+			}
+			var herr *obsv.HarnessError
+			if !errors.As(err, &herr) || herr.Class != obsv.ClassMismatch || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("RunIteration error %v, want a mismatch containing %q", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package workload
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Table naming strategies accepted by -table-naming.
+const (
+	namingTime   = "time"
+	namingRandom = "random"
+	namingULID   = "ulid"
+)
+
+// namingStrategies lists the strategies accepted by -table-naming.
+var namingStrategies = []string{namingULID, namingRandom, namingTime}
+
+// tableNameAttempts bounds the names tried when a generated one is
+// already in use.
+const tableNameAttempts = 5
+
+// tableName returns a new table name under prefix. Time names only
+// change once a second, so iterations faster than that or concurrent
+// runs collide on them; random and ULID names do not, and ULIDs still
+// sort by creation time.
+func tableName(strategy, prefix string, now time.Time) string {
+	switch strategy {
+	case namingTime:
+		return prefix + now.Format("150405")
+	case namingRandom:
+		return prefix + fmt.Sprintf("%012x", rand.Uint64()>>16)
+	default:
+		return prefix + newULID(now)
+	}
+}
+
+// resourceName returns a new name under prefix for a stream, queue,
+// topic or bucket, made the way -table-naming makes table names.
+func (h *Harness) resourceName(prefix string) string {
+	return tableName(h.Flags.TableNaming, prefix, time.Now())
+}
+
+// crockford is the alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID for now: 48 bits of Unix milliseconds then 80
+// random bits, as 26 base32 characters.
+func newULID(now time.Time) string {
+	var id [16]byte
+	ms := uint64(now.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+	binary.BigEndian.PutUint16(id[6:], uint16(rand.Uint32()))
+	binary.BigEndian.PutUint64(id[8:], rand.Uint64())
+
+	// 128 bits as 26 five-bit digits, the first holding only 3 bits.
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
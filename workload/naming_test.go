@@ -0,0 +1,90 @@
+package workload
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestTableNames(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC)
+	if got := tableName(namingTime, "MyTable", now); got != "MyTable123045" {
+		t.Errorf("time name %q, want MyTable123045", got)
+	}
+
+	seen := make(map[string]bool)
+	for range 1000 {
+		for _, strategy := range []string{namingRandom, namingULID} {
+			name := tableName(strategy, "MyTable", now)
+			if seen[name] {
+				t.Fatalf("%s name %q generated twice", strategy, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	id := newULID(now)
+	if len(id) != 26 || strings.Trim(id, crockford) != "" {
+		t.Fatalf("ULID %q is not 26 Crockford base32 characters", id)
+	}
+	// The first ten characters are the timestamp, so ULIDs sort by time.
+	if later := newULID(now.Add(time.Millisecond)); id[:10] >= later[:10] {
+		t.Errorf("ULID %q does not sort before %q, made a millisecond later", id, later)
+	}
+	if want := "01HWT25H88"; id[:10] != want {
+		t.Errorf("ULID timestamp %q, want %q", id[:10], want)
+	}
+}
+
+// takenNames reports the first taken CreateTable calls' names as
+// already in use.
+type takenNames struct {
+	*ddbtest.Memory
+	taken int
+	tried []string
+}
+
+func (n *takenNames) CreateTable(ctx context.Context, in *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	n.tried = append(n.tried, aws.ToString(in.TableName))
+	if len(n.tried) <= n.taken {
+		return nil, &types.ResourceInUseException{Message: aws.String("Table already exists: " + aws.ToString(in.TableName))}
+	}
+	return n.Memory.CreateTable(ctx, in, optFns...)
+}
+
+func TestCreateTableRetriesTakenNames(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-quiet")
+	client := &takenNames{Memory: h.Memory, taken: 2}
+	w := &dynamoWorkload{Harness: h, client: client}
+	if err := w.RunIteration(context.Background()); err != nil {
+		t.Fatalf("iteration: %v", err)
+	}
+	tried := client.tried
+	if len(tried) != 3 || tried[0] == tried[1] || tried[1] == tried[2] || h.Checkpoint.Table != tried[2] {
+		t.Errorf("tried %q and created %q, want three different names and the last one used", tried, h.Checkpoint.Table)
+	}
+
+	client = &takenNames{Memory: h.Memory, taken: tableNameAttempts}
+	w.client = client
+	if _, err := w.createNamedTable(context.Background(), h.Flags.TablePrefix); err == nil || len(client.tried) != tableNameAttempts {
+		t.Errorf("%d names tried, error %v; want %d tried and the collision reported", len(client.tried), err, tableNameAttempts)
+	}
+}
+
+func TestResourceNames(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-quiet")
+	seen := make(map[string]bool)
+	for range 100 {
+		name := h.resourceName("MyQueue")
+		if !strings.HasPrefix(name, "MyQueue") || seen[name] {
+			t.Fatalf("resource name %q is not new under MyQueue", name)
+		}
+		seen[name] = true
+	}
+}
@@ -0,0 +1,109 @@
+package workload
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// writeJob writes count items numbered from first.
+type writeJob struct {
+	first, count int
+}
+
+// workerStats is what one pool worker did.
+type workerStats struct {
+	jobs   int
+	items  int
+	errors int
+	busy   time.Duration
+}
+
+// writePool runs writes on a fixed number of goroutines fed from a
+// bounded queue. submit blocks while the queue is full, so seeding runs
+// no further ahead of the backend than the queue allows. The first
+// failed write cancels the rest.
+type writePool struct {
+	write func(ctx context.Context, job writeJob) error
+	jobs  chan writeJob
+	ctx   context.Context
+
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	workers []workerStats
+	stalls  int
+
+	errOnce sync.Once
+	err     error
+}
+
+// newWritePool starts workers goroutines that call write for each
+// submitted job, with room for queue jobs waiting between them.
+func newWritePool(ctx context.Context, workers, queue int, write func(ctx context.Context, job writeJob) error) *writePool {
+	p := &writePool{
+		write:   write,
+		jobs:    make(chan writeJob, queue),
+		workers: make([]workerStats, workers),
+	}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.wg.Add(workers)
+	for i := range p.workers {
+		go p.work(&p.workers[i])
+	}
+	return p
+}
+
+func (p *writePool) work(s *workerStats) {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		if p.ctx.Err() != nil {
+			continue
+		}
+		start := time.Now()
+		err := p.write(p.ctx, job)
+		s.busy += time.Since(start)
+		s.jobs++
+		if err != nil {
+			s.errors++
+			p.fail(err)
+			continue
+		}
+		s.items += job.count
+	}
+}
+
+func (p *writePool) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// submit queues job, waiting while the queue is full. It fails once a
+// write has failed or ctx is done.
+func (p *writePool) submit(job writeJob) error {
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+	}
+	p.stalls++
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// wait stops taking jobs, waits for the queued ones and returns the
+// first write error, or ctx's error if it ended the pool early.
+func (p *writePool) wait() error {
+	close(p.jobs)
+	p.wg.Wait()
+	defer p.cancel()
+	if p.err != nil {
+		return p.err
+	}
+	return p.ctx.Err()
+}
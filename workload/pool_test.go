@@ -0,0 +1,60 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWritePoolBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	p := newWritePool(context.Background(), 1, 1, func(ctx context.Context, job writeJob) error {
+		<-release
+		return nil
+	})
+	// The worker holds at most one job and the queue one more, so the
+	// third submit has to wait.
+	submitted := make(chan struct{})
+	go func() {
+		for i := range 3 {
+			p.submit(writeJob{first: i, count: 1})
+		}
+		close(submitted)
+	}()
+	close(release)
+	<-submitted
+	if p.stalls == 0 {
+		t.Error("submit never waited on a full queue")
+	}
+	if err := p.wait(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.workers[0]; s.jobs != 3 || s.items != 3 {
+		t.Errorf("worker stats %+v, want 3 jobs and 3 items", s)
+	}
+}
+
+func TestWritePoolStopsOnError(t *testing.T) {
+	failed := errors.New("write failed")
+	p := newWritePool(context.Background(), 2, 4, func(ctx context.Context, job writeJob) error {
+		if job.first == 3 {
+			return failed
+		}
+		return nil
+	})
+	for i := 0; i < 100; i++ {
+		if p.submit(writeJob{first: i, count: 1}) != nil {
+			break
+		}
+	}
+	if err := p.wait(); !errors.Is(err, failed) {
+		t.Fatalf("wait: got %v, want %v", err, failed)
+	}
+	items := 0
+	for _, s := range p.workers {
+		items += s.items
+	}
+	if items >= 99 {
+		t.Errorf("%d items written after the failure, want the pool to stop early", items)
+	}
+}
@@ -0,0 +1,98 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Backoff between readiness probes.
+const (
+	probeInitialDelay = 250 * time.Millisecond
+	probeMaxDelay     = 5 * time.Second
+)
+
+// WaitReady probes the endpoint with backoff until it answers or
+// -ready-timeout passes, so a backend that is still starting fails with
+// a clear error rather than from the first workload call.
+func WaitReady(ctx context.Context, h *Harness) error {
+	if h.Flags.ReadyTimeout <= 0 || h.Memory != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.Flags.ReadyTimeout)
+	defer cancel()
+
+	start := time.Now()
+	delay := probeInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := probe(ctx, h)
+		if err == nil {
+			h.Logger.Operationf("Backend ready at %s after %s", h.endpoint(), time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		h.Logger.Operationf("Waiting for backend at %s: %v", h.endpoint(), err)
+
+		select {
+		case <-ctx.Done():
+			return &obsv.HarnessError{
+				Class: obsv.ClassUnavailable,
+				Op:    "ProbeEndpoint",
+				Err: fmt.Errorf("backend not ready at %s after %s (%d attempts): %w",
+					h.endpoint(), h.Flags.ReadyTimeout, attempt, err),
+			}
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, probeMaxDelay)
+	}
+}
+
+// probe asks LocalStack's health endpoint and, where there is none,
+// falls back to a ListTables call.
+func probe(ctx context.Context, h *Harness) error {
+	status, _, err := localStackGet(ctx, h, "/_localstack/health")
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound, http.StatusForbidden:
+		// Not LocalStack; any signed API call proves the service is up.
+	default:
+		return fmt.Errorf("health check returned HTTP %d", status)
+	}
+
+	client := h.DynamoClient()
+	return obsv.Timed(ctx, h.Stats, "ListTables", h.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := client.ListTables(ctx, &dynamodb.ListTablesInput{Limit: aws.Int32(1)})
+		return err
+	})
+}
+
+// localStackGet fetches one of LocalStack's internal endpoints, such as
+// /_localstack/health, returning its status and body.
+func localStackGet(ctx context.Context, h *Harness, path string) (int, []byte, error) {
+	ctx, cancel := obsv.OpContext(ctx, h.Flags.ControlTimeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(h.endpoint(), "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := h.AWS.HTTPClient.Do(req)
+	h.Endpoints.observe(err)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, body, err
+}
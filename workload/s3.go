@@ -0,0 +1,273 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Workload creates a bucket, uploads an object in parts, reads a
+// byte range back and deletes everything again.
+type s3Workload struct {
+	*Harness
+	client *s3.Client
+	// part is reused to generate each uploaded part.
+	part []byte
+}
+
+func (w *s3Workload) Name() string { return "s3" }
+
+// RunIteration exercises one bucket lifecycle.
+func (w *s3Workload) RunIteration(ctx context.Context) error {
+	// Bucket names must be lower case.
+	bucket := strings.ToLower(w.resourceName("mybucket"))
+	key := "object.bin"
+
+	if err := w.createBucket(ctx, bucket); err != nil {
+		return err
+	}
+	if err := w.multipartUpload(ctx, bucket, key); err != nil {
+		return err
+	}
+	if err := w.rangeGet(ctx, bucket, key); err != nil {
+		return err
+	}
+	if w.Flags.S3MultiRegionAccessPoint != "" {
+		if err := w.accessPointRoundTrip(ctx, key); err != nil {
+			return err
+		}
+	}
+	return w.deleteBucket(ctx, bucket, key)
+}
+
+// accessPointRoundTrip writes a small object through -s3-mrap, reads it
+// back and deletes it. The SDK signs these requests with SigV4a.
+func (w *s3Workload) accessPointRoundTrip(ctx context.Context, key string) error {
+	arn := w.Flags.S3MultiRegionAccessPoint
+	body := objectBytes(0, 1024)
+	// Access point ARNs cannot be addressed by path.
+	virtualHost := func(o *s3.Options) { o.UsePathStyle = false }
+
+	err := obsv.Timed(ctx, w.Stats, "MRAPPutObject", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &arn,
+			Key:    &key,
+			Body:   bytes.NewReader(body),
+		}, virtualHost)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var got []byte
+	err = obsv.Timed(ctx, w.Stats, "MRAPGetObject", w.Flags.DataTimeout, func(ctx context.Context) error {
+		out, err := w.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &arn, Key: &key}, virtualHost)
+		if err != nil {
+			return err
+		}
+		defer out.Body.Close()
+		got, err = io.ReadAll(out.Body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, body) {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "MRAPGetObject",
+			Err:   fmt.Errorf("%s/%s: read back %d bytes that differ from the %d written", arn, key, len(got), len(body)),
+		}
+	}
+
+	err = obsv.Timed(ctx, w.Stats, "MRAPDeleteObject", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &arn, Key: &key}, virtualHost)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Round-tripped %s through access point %s", key, arn)
+	return nil
+}
+
+func (w *s3Workload) createBucket(ctx context.Context, bucket string) error {
+	input := &s3.CreateBucketInput{Bucket: &bucket}
+	if w.AWS.Region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(w.AWS.Region),
+		}
+	}
+	err := obsv.Timed(ctx, w.Stats, "CreateBucket", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.CreateBucket(ctx, input)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Bucket created: %s", bucket)
+	return nil
+}
+
+// multipartUpload uploads -s3-object-size bytes of generated content in
+// -s3-part-size parts, aborting the upload if any part fails.
+func (w *s3Workload) multipartUpload(ctx context.Context, bucket, key string) error {
+	var upload *s3.CreateMultipartUploadOutput
+	err := obsv.Timed(ctx, w.Stats, "CreateMultipartUpload", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		upload, err = w.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket: &bucket,
+			Key:    &key,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var parts []types.CompletedPart
+	for offset, n := int64(0), int32(1); offset < w.Flags.S3ObjectSize; offset, n = offset+w.Flags.S3PartSize, n+1 {
+		size := min(w.Flags.S3PartSize, w.Flags.S3ObjectSize-offset)
+		w.part = appendObjectBytes(w.part[:0], offset, size)
+		var part *s3.UploadPartOutput
+		err := obsv.Timed(ctx, w.Stats, "UploadPart", w.Flags.DataTimeout, func(ctx context.Context) error {
+			var err error
+			part, err = w.client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     &bucket,
+				Key:        &key,
+				UploadId:   upload.UploadId,
+				PartNumber: aws.Int32(n),
+				Body:       bytes.NewReader(w.part),
+			})
+			return err
+		})
+		if err != nil {
+			w.abortUpload(ctx, bucket, key, upload.UploadId)
+			return err
+		}
+		parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(n)})
+	}
+
+	err = obsv.Timed(ctx, w.Stats, "CompleteMultipartUpload", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          &bucket,
+			Key:             &key,
+			UploadId:        upload.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+		})
+		return err
+	})
+	if err != nil {
+		w.abortUpload(ctx, bucket, key, upload.UploadId)
+		return err
+	}
+	w.Logger.Operationf("Uploaded %s/%s in %d parts (%d bytes)", bucket, key, len(parts), w.Flags.S3ObjectSize)
+	return nil
+}
+
+func (w *s3Workload) abortUpload(ctx context.Context, bucket, key string, uploadID *string) {
+	_ = obsv.Timed(ctx, w.Stats, "AbortMultipartUpload", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &key,
+			UploadId: uploadID,
+		})
+		return err
+	})
+}
+
+// rangeGet reads a range spanning the first part boundary, or the middle
+// of the object if it fits in one part, and checks the returned bytes.
+func (w *s3Workload) rangeGet(ctx context.Context, bucket, key string) error {
+	length := min(int64(1024), w.Flags.S3ObjectSize)
+	first := (w.Flags.S3ObjectSize - length) / 2
+	if w.Flags.S3ObjectSize > w.Flags.S3PartSize {
+		first = w.Flags.S3PartSize - length/2
+	}
+	last := first + length - 1
+
+	var body []byte
+	err := obsv.Timed(ctx, w.Stats, "GetObject", w.Flags.DataTimeout, func(ctx context.Context) error {
+		resp, err := w.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &key,
+			Range:  aws.String(fmt.Sprintf("bytes=%d-%d", first, last)),
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(body, objectBytes(first, length)) {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "GetObject",
+			Err:   fmt.Errorf("%s/%s bytes %d-%d: got %d bytes that differ from what was uploaded", bucket, key, first, last, len(body)),
+		}
+	}
+	w.Logger.Operationf("Fetched range %d-%d of %s/%s", first, last, bucket, key)
+	return nil
+}
+
+func (w *s3Workload) deleteBucket(ctx context.Context, bucket, key string) error {
+	err := obsv.Timed(ctx, w.Stats, "DeleteObject", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: &bucket, Key: &key})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = obsv.Timed(ctx, w.Stats, "DeleteBucket", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: &bucket})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Bucket deleted: %s", bucket)
+	return nil
+}
+
+// objectPattern is one period of the generated object content.
+var objectPattern = func() []byte {
+	b := make([]byte, 251)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}()
+
+// objectBytes returns the generated content of the object at
+// [offset, offset+size), so any range can be checked without keeping
+// the whole object in memory.
+func objectBytes(offset, size int64) []byte {
+	return appendObjectBytes(nil, offset, size)
+}
+
+// appendObjectBytes appends the content at [offset, offset+size) to dst,
+// so a caller can generate part after part into one buffer.
+func appendObjectBytes(dst []byte, offset, size int64) []byte {
+	dst = slices.Grow(dst, int(size))
+	for start := offset % int64(len(objectPattern)); size > 0; start = 0 {
+		chunk := objectPattern[start:min(int64(len(objectPattern)), start+size)]
+		dst = append(dst, chunk...)
+		size -= int64(len(chunk))
+	}
+	return dst
+}
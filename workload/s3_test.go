@@ -0,0 +1,27 @@
+package workload
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppendObjectBytes(t *testing.T) {
+	for _, r := range []struct{ offset, size int64 }{{0, 1}, {250, 3}, {1000, 600}, {502, 251}} {
+		want := make([]byte, r.size)
+		for i := range want {
+			want[i] = byte((r.offset + int64(i)) % 251)
+		}
+		if got := appendObjectBytes([]byte("x"), r.offset, r.size); !bytes.Equal(got[1:], want) || got[0] != 'x' {
+			t.Errorf("bytes %d+%d differ from the generated pattern", r.offset, r.size)
+		}
+	}
+}
+
+func BenchmarkObjectBytes(b *testing.B) {
+	var buf []byte
+	b.ReportAllocs()
+	b.SetBytes(5 << 20)
+	for offset := int64(0); b.Loop(); offset += 5 << 20 {
+		buf = appendObjectBytes(buf[:0], offset, 5<<20)
+	}
+}
@@ -0,0 +1,30 @@
+package workload
+
+import (
+	"context"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+)
+
+// snapshotter returns a snapshotter for the harness's DynamoDB backend.
+func (h *Harness) snapshotter() *ddbtest.Snapshotter {
+	return &ddbtest.Snapshotter{
+		Client:   h.DynamoClient(),
+		Stats:    h.Stats,
+		Logger:   h.Logger,
+		Timeouts: h.Flags.Timeouts(),
+	}
+}
+
+// TakeSnapshot dumps every table the run created to path.
+func TakeSnapshot(ctx context.Context, h *Harness, path string) error {
+	return h.snapshotter().Take(ctx, path, h.Checkpoint.Tables)
+}
+
+// RestoreSnapshot recreates the tables in path and loads their items,
+// adding them to the run's tables.
+func RestoreSnapshot(ctx context.Context, h *Harness, path string) error {
+	names, err := h.snapshotter().Restore(ctx, path)
+	h.Checkpoint.Tables = append(h.Checkpoint.Tables, names...)
+	return err
+}
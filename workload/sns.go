@@ -0,0 +1,162 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// snsWorkload publishes to a topic fanned out to an SQS queue and checks
+// that every message is delivered.
+type snsWorkload struct {
+	*Harness
+	client *sns.Client
+	queues *sqsWorkload
+}
+
+func (w *snsWorkload) Name() string { return "sns" }
+
+// RunIteration exercises one topic-to-queue fan-out.
+func (w *snsWorkload) RunIteration(ctx context.Context) error {
+	topicName := w.resourceName("MyTopic")
+	queueName := w.resourceName("MyTopicQueue")
+
+	topicARN, err := w.createTopic(ctx, topicName)
+	if err != nil {
+		return err
+	}
+	queueURL, err := w.queues.createQueue(ctx, queueName)
+	if err != nil {
+		return err
+	}
+	subscriptionARN, err := w.subscribe(ctx, topicARN, queueURL)
+	if err != nil {
+		return err
+	}
+
+	published := make(map[string]bool, w.Flags.SNSMessages)
+	for n := 0; n < w.Flags.SNSMessages; n++ {
+		body := fmt.Sprintf("notification %d", n)
+		if err := w.publish(ctx, topicARN, body); err != nil {
+			return err
+		}
+		published[body] = true
+	}
+	w.Logger.Operationf("Published %d messages to %s", len(published), topicName)
+
+	// Raw delivery puts the published message straight into the body.
+	body := func(m sqstypes.Message) string { return aws.ToString(m.Body) }
+	if err := w.queues.receiveAll(ctx, queueURL, published, body, "SNSToSQSDelivery"); err != nil {
+		return err
+	}
+
+	err = obsv.Timed(ctx, w.Stats, "Unsubscribe", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.Unsubscribe(ctx, &sns.UnsubscribeInput{SubscriptionArn: &subscriptionARN})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = obsv.Timed(ctx, w.Stats, "DeleteTopic", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.DeleteTopic(ctx, &sns.DeleteTopicInput{TopicArn: &topicARN})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Topic deleted: %s", topicName)
+	return w.queues.deleteQueue(ctx, queueURL, queueName)
+}
+
+func (w *snsWorkload) createTopic(ctx context.Context, topicName string) (string, error) {
+	var out *sns.CreateTopicOutput
+	err := obsv.Timed(ctx, w.Stats, "CreateTopic", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.CreateTopic(ctx, &sns.CreateTopicInput{Name: &topicName})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	w.Logger.Operationf("Topic created: %s", topicName)
+	return aws.ToString(out.TopicArn), nil
+}
+
+// subscribe lets the topic deliver to the queue and subscribes it with
+// raw message delivery, returning the subscription ARN.
+func (w *snsWorkload) subscribe(ctx context.Context, topicARN, queueURL string) (string, error) {
+	var attrs *sqs.GetQueueAttributesOutput
+	err := obsv.Timed(ctx, w.Stats, "GetQueueAttributes", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		attrs, err = w.queues.client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       &queueURL,
+			AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameQueueArn},
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	queueARN := attrs.Attributes[string(sqstypes.QueueAttributeNameQueueArn)]
+
+	policy := queuePolicy(queueARN, topicARN)
+	err = obsv.Timed(ctx, w.Stats, "SetQueueAttributes", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.queues.client.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl:   &queueURL,
+			Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): policy},
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var out *sns.SubscribeOutput
+	err = obsv.Timed(ctx, w.Stats, "Subscribe", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.Subscribe(ctx, &sns.SubscribeInput{
+			TopicArn:              &topicARN,
+			Protocol:              aws.String("sqs"),
+			Endpoint:              &queueARN,
+			Attributes:            map[string]string{"RawMessageDelivery": "true"},
+			ReturnSubscriptionArn: true,
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	w.Logger.Operationf("Subscribed %s to %s", queueARN, topicARN)
+	return aws.ToString(out.SubscriptionArn), nil
+}
+
+// queuePolicy lets the topic, and only it, send to the queue.
+func queuePolicy(queueARN, topicARN string) string {
+	return fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow",`+
+		`"Principal":{"Service":"sns.amazonaws.com"},"Action":"sqs:SendMessage","Resource":%q,`+
+		`"Condition":{"ArnEquals":{"aws:SourceArn":%q}}}]}`, queueARN, topicARN)
+}
+
+func (w *snsWorkload) publish(ctx context.Context, topicARN, body string) error {
+	return obsv.Timed(ctx, w.Stats, "Publish", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.Publish(ctx, &sns.PublishInput{
+			TopicArn: &topicARN,
+			Message:  &body,
+			MessageAttributes: map[string]snstypes.MessageAttributeValue{
+				sentAtAttribute: {
+					DataType:    aws.String("Number"),
+					StringValue: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+				},
+			},
+		})
+		return err
+	})
+}
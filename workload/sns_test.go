@@ -0,0 +1,35 @@
+package workload
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQueuePolicy(t *testing.T) {
+	const (
+		queueARN = "arn:aws:sqs:us-east-1:000000000000:MyTopicQueue01HWT25H88"
+		topicARN = "arn:aws:sns:us-east-1:000000000000:MyTopic01HWT25H88"
+	)
+	var policy struct {
+		Statement []struct {
+			Effect    string
+			Principal struct{ Service string }
+			Action    string
+			Resource  string
+			Condition struct {
+				ArnEquals map[string]string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(queuePolicy(queueARN, topicARN)), &policy); err != nil {
+		t.Fatalf("policy is not JSON: %v", err)
+	}
+	if len(policy.Statement) != 1 {
+		t.Fatalf("policy has %d statements, want 1", len(policy.Statement))
+	}
+	s := policy.Statement[0]
+	if s.Effect != "Allow" || s.Principal.Service != "sns.amazonaws.com" || s.Action != "sqs:SendMessage" ||
+		s.Resource != queueARN || s.Condition.ArnEquals["aws:SourceArn"] != topicARN {
+		t.Errorf("statement %+v does not let only %s send to %s", s, topicARN, queueARN)
+	}
+}
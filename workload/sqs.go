@@ -0,0 +1,264 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// sentAtAttribute carries the send time so receivers can measure
+// end-to-end latency against the same clock.
+const sentAtAttribute = "SentAtUnixNano"
+
+// sqsWorkload creates a queue, sends batches of messages and long-polls
+// them back, extending visibility when processing runs long.
+type sqsWorkload struct {
+	*Harness
+	client *sqs.Client
+}
+
+func (w *sqsWorkload) Name() string { return "sqs" }
+
+// RunIteration exercises one queue lifecycle.
+func (w *sqsWorkload) RunIteration(ctx context.Context) error {
+	queueName := w.resourceName("MyQueue")
+	queueURL, err := w.createQueue(ctx, queueName)
+	if err != nil {
+		return err
+	}
+
+	sent := make(map[string]bool, w.Flags.SQSMessages)
+	for n := 0; n < w.Flags.SQSMessages; n += w.Flags.SQSBatchSize {
+		ids, err := w.sendBatch(ctx, queueURL, n, min(w.Flags.SQSBatchSize, w.Flags.SQSMessages-n))
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			sent[id] = true
+		}
+	}
+	w.Logger.Operationf("Sent %d messages to %s", len(sent), queueName)
+
+	messageID := func(m types.Message) string { return aws.ToString(m.MessageId) }
+	if err := w.receiveAll(ctx, queueURL, sent, messageID, "SQSEndToEnd"); err != nil {
+		return err
+	}
+	return w.deleteQueue(ctx, queueURL, queueName)
+}
+
+func (w *sqsWorkload) createQueue(ctx context.Context, queueName string) (string, error) {
+	var out *sqs.CreateQueueOutput
+	err := obsv.Timed(ctx, w.Stats, "CreateQueue", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.CreateQueue(ctx, &sqs.CreateQueueInput{
+			QueueName: &queueName,
+			Attributes: map[string]string{
+				string(types.QueueAttributeNameVisibilityTimeout): strconv.Itoa(int(w.Flags.SQSVisibilityTimeout.Seconds())),
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	w.Logger.Operationf("Queue created: %s", queueName)
+	return aws.ToString(out.QueueUrl), nil
+}
+
+// sendBatch sends count messages numbered from first and returns the
+// message IDs the queue accepted.
+func (w *sqsWorkload) sendBatch(ctx context.Context, queueURL string, first, count int) ([]string, error) {
+	entries := make([]types.SendMessageBatchRequestEntry, count)
+	for i := range entries {
+		entries[i] = types.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(i)),
+			MessageBody: aws.String(fmt.Sprintf("message %d", first+i)),
+			MessageAttributes: map[string]types.MessageAttributeValue{
+				sentAtAttribute: {
+					DataType:    aws.String("Number"),
+					StringValue: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+				},
+			},
+		}
+	}
+
+	var out *sqs.SendMessageBatchOutput
+	err := obsv.Timed(ctx, w.Stats, "SendMessageBatch", w.Flags.DataTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: &queueURL,
+			Entries:  entries,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Failed) > 0 {
+		f := out.Failed[0]
+		return nil, obsv.Classify("SendMessageBatch", fmt.Errorf("%d of %d entries failed, first: %s %s",
+			len(out.Failed), count, aws.ToString(f.Code), aws.ToString(f.Message)))
+	}
+
+	ids := make([]string, len(out.Successful))
+	for i, s := range out.Successful {
+		ids[i] = aws.ToString(s.MessageId)
+	}
+	return ids, nil
+}
+
+// receiveAll long-polls until every message in sent, identified by key,
+// has been received and deleted, giving up after two consecutive empty
+// polls. Delivery latency is recorded in stats as latencyOp.
+func (w *sqsWorkload) receiveAll(ctx context.Context, queueURL string, sent map[string]bool,
+	key func(types.Message) string, latencyOp string) error {
+	waitSeconds := int32(w.Flags.SQSWait.Seconds())
+	visibility := int32(w.Flags.SQSVisibilityTimeout.Seconds())
+	remaining := len(sent)
+	redelivered := 0
+
+	for empty := 0; remaining > 0 && empty < 2; {
+		var out *sqs.ReceiveMessageOutput
+		// The call legitimately blocks for the long-poll wait on top of
+		// the usual data-plane budget.
+		err := obsv.Timed(ctx, w.Stats, "ReceiveMessage", w.Flags.DataTimeout+w.Flags.SQSWait, func(ctx context.Context) error {
+			var err error
+			out, err = w.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+				QueueUrl:                    &queueURL,
+				MaxNumberOfMessages:         10,
+				WaitTimeSeconds:             waitSeconds,
+				VisibilityTimeout:           visibility,
+				MessageAttributeNames:       []string{sentAtAttribute},
+				MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount},
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		receivedAt := time.Now()
+		if len(out.Messages) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		for _, m := range out.Messages {
+			if attr, ok := m.MessageAttributes[sentAtAttribute]; ok {
+				if ns, err := strconv.ParseInt(aws.ToString(attr.StringValue), 10, 64); err == nil {
+					w.Stats.Record(latencyOp, receivedAt.Sub(time.Unix(0, ns)), nil)
+				}
+			}
+			if m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)] != "1" {
+				redelivered++
+			}
+		}
+
+		// Simulated processing, extending visibility every half timeout so
+		// the messages are not redelivered to another receiver before
+		// they are deleted.
+		err = processBatch(ctx, w.Flags.SQSProcessTime, w.Flags.SQSVisibilityTimeout/2, func(ctx context.Context) error {
+			return w.extendVisibility(ctx, queueURL, out.Messages, visibility)
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := w.deleteBatch(ctx, queueURL, out.Messages); err != nil {
+			return err
+		}
+		for _, m := range out.Messages {
+			if id := key(m); sent[id] {
+				delete(sent, id)
+				remaining--
+			}
+		}
+	}
+
+	if remaining > 0 {
+		return &obsv.HarnessError{
+			Class: obsv.ClassMismatch,
+			Op:    "ReceiveMessage",
+			Err:   fmt.Errorf("%s: %d messages were sent but never received", queueURL, remaining),
+		}
+	}
+	w.Logger.Operationf("Received and deleted all messages (%d redelivered)", redelivered)
+	return nil
+}
+
+// processBatch waits out processTime, calling extend every heartbeat
+// while it runs. It returns early if extend fails or ctx ends.
+func processBatch(ctx context.Context, processTime, heartbeat time.Duration, extend func(context.Context) error) error {
+	done := time.NewTimer(processTime)
+	defer done.Stop()
+	beat := time.NewTicker(heartbeat)
+	defer beat.Stop()
+	for {
+		select {
+		case <-done.C:
+			return nil
+		case <-beat.C:
+			if err := extend(ctx); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *sqsWorkload) extendVisibility(ctx context.Context, queueURL string, msgs []types.Message, visibility int32) error {
+	entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, len(msgs))
+	for i, m := range msgs {
+		entries[i] = types.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			ReceiptHandle:     m.ReceiptHandle,
+			VisibilityTimeout: visibility,
+		}
+	}
+	return obsv.Timed(ctx, w.Stats, "ChangeMessageVisibilityBatch", w.Flags.DataTimeout, func(ctx context.Context) error {
+		_, err := w.client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: &queueURL,
+			Entries:  entries,
+		})
+		return err
+	})
+}
+
+func (w *sqsWorkload) deleteBatch(ctx context.Context, queueURL string, msgs []types.Message) error {
+	entries := make([]types.DeleteMessageBatchRequestEntry, len(msgs))
+	for i, m := range msgs {
+		entries[i] = types.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: m.ReceiptHandle,
+		}
+	}
+	return obsv.Timed(ctx, w.Stats, "DeleteMessageBatch", w.Flags.DataTimeout, func(ctx context.Context) error {
+		out, err := w.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: &queueURL,
+			Entries:  entries,
+		})
+		if err == nil && len(out.Failed) > 0 {
+			err = fmt.Errorf("%d of %d deletes failed, first: %s", len(out.Failed), len(entries), aws.ToString(out.Failed[0].Message))
+		}
+		return err
+	})
+}
+
+func (w *sqsWorkload) deleteQueue(ctx context.Context, queueURL, queueName string) error {
+	err := obsv.Timed(ctx, w.Stats, "DeleteQueue", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		_, err := w.client.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: &queueURL})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	w.Logger.Operationf("Queue deleted: %s", queueName)
+	return nil
+}
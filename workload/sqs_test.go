@@ -0,0 +1,39 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProcessBatch(t *testing.T) {
+	failed := errors.New("ChangeMessageVisibilityBatch failed")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		name        string
+		ctx         context.Context
+		processTime time.Duration
+		extendErr   error
+		extends     int
+		err         error
+	}{
+		{name: "within visibility", ctx: context.Background(), processTime: 10 * time.Millisecond},
+		{name: "outlasts visibility", ctx: context.Background(), processTime: 110 * time.Millisecond, extends: 2},
+		{name: "extend fails", ctx: context.Background(), processTime: time.Second, extendErr: failed, extends: 1, err: failed},
+		{name: "cancelled", ctx: cancelled, processTime: time.Hour, err: context.Canceled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			extends := 0
+			err := processBatch(tc.ctx, tc.processTime, 40*time.Millisecond, func(context.Context) error {
+				extends++
+				return tc.extendErr
+			})
+			if !errors.Is(err, tc.err) || extends != tc.extends {
+				t.Errorf("processBatch extended %d times and returned %v, want %d and %v", extends, err, tc.extends, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SSMConfigSource reads flag values from a Parameter Store path. Each
+// parameter below the path sets the flag named by its relative name,
+// with slashes read as dashes, so /harness/sqs/messages sets
+// -sqs-messages. Flags given on the command line always win.
+type SSMConfigSource struct {
+	client  *ssm.Client
+	path    string
+	name    string
+	args    []string
+	stats   *obsv.Stats
+	timeout time.Duration
+
+	mu     sync.Mutex
+	values map[string]string
+	next   *Config
+}
+
+// LoadSSMConfig reads -ssm-path through an SDK config for the
+// command-line endpoint and bootstrap credentials, and returns the
+// configuration it produces, from name and args, together with the
+// source for later polling.
+func LoadSSMConfig(ctx context.Context, cfg aws.Config, name string, args []string, flags *Config,
+	stats *obsv.Stats) (*SSMConfigSource, *Config, error) {
+	s := &SSMConfigSource{
+		client:  ssm.NewFromConfig(cfg),
+		path:    flags.SSMPath,
+		name:    name,
+		args:    args,
+		stats:   stats,
+		timeout: flags.ControlTimeout,
+	}
+
+	values, err := s.fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	next, err := s.parse(values)
+	if err != nil {
+		return nil, nil, &obsv.HarnessError{Class: obsv.ClassConfig, Op: "GetParametersByPath", Err: err}
+	}
+	s.values = values
+	return s, next, nil
+}
+
+// fetch returns every parameter below the path keyed by flag name.
+func (s *SSMConfigSource) fetch(ctx context.Context) (map[string]string, error) {
+	paginator := ssm.NewGetParametersByPathPaginator(s.client, &ssm.GetParametersByPathInput{
+		Path:           &s.path,
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+
+	values := make(map[string]string)
+	for paginator.HasMorePages() {
+		var page *ssm.GetParametersByPathOutput
+		err := obsv.Timed(ctx, s.stats, "GetParametersByPath", s.timeout, func(ctx context.Context) error {
+			var err error
+			page, err = paginator.NextPage(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.Parameters {
+			name := strings.Trim(strings.TrimPrefix(aws.ToString(p.Name), s.path), "/")
+			values[strings.ReplaceAll(name, "/", "-")] = aws.ToString(p.Value)
+		}
+	}
+	return values, nil
+}
+
+// parse applies values ahead of the command line, so a flag given on
+// both takes the command-line value.
+func (s *SSMConfigSource) parse(values map[string]string) (*Config, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names)+len(s.args))
+	for _, name := range names {
+		args = append(args, "-"+name+"="+values[name])
+	}
+	cfg, err := ParseFlags(s.name, append(args, s.args...))
+	if err != nil {
+		return nil, fmt.Errorf("parameters under %s: %w", s.path, err)
+	}
+	return cfg, nil
+}
+
+// Watch re-reads the path every interval. When a parameter has changed
+// the new configuration is parsed and held for RunLoop to take; bad
+// values are logged and the running settings kept.
+func (s *SSMConfigSource) Watch(ctx context.Context, interval time.Duration, logger *obsv.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			values, err := s.fetch(ctx)
+			if err != nil {
+				logger.Operationf("Failed to read %s: %v", s.path, err)
+				continue
+			}
+			if maps.Equal(values, s.values) {
+				continue
+			}
+			s.values = values
+
+			cfg, err := s.parse(values)
+			if err != nil {
+				logger.Operationf("Ignoring changed parameters: %v", err)
+				continue
+			}
+			s.mu.Lock()
+			s.next = cfg
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// Take returns the configuration read since the last call, if any. A
+// nil source never has one.
+func (s *SSMConfigSource) Take() *Config {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cfg := s.next
+	s.next = nil
+	return cfg
+}
+
+// applyWorkloadSettings copies the settings that may change mid-run
+// from src into dst and returns the flags that changed. Everything else
+// takes effect on the next start; of that, ignored names the table
+// lifecycle flags that changed, since flipping them mid-run would leave
+// the table in use undeleted.
+func applyWorkloadSettings(dst, src *Config) (changed, ignored []string) {
+	update(&changed, "items", &dst.Items, src.Items)
+	update(&changed, "write-workers", &dst.WriteWorkers, src.WriteWorkers)
+	update(&changed, "write-queue", &dst.WriteQueue, src.WriteQueue)
+	update(&changed, "write-batch", &dst.WriteBatch, src.WriteBatch)
+	update(&changed, "write-transact", &dst.WriteTransact, src.WriteTransact)
+	update(&changed, "write-flush-interval", &dst.WriteFlushInterval, src.WriteFlushInterval)
+	update(&changed, "interval", &dst.Interval, src.Interval)
+	update(&changed, "iteration-budget", &dst.IterationBudget, src.IterationBudget)
+	update(&changed, "verify-mode", &dst.VerifyMode, src.VerifyMode)
+	update(&changed, "scan-page-size", &dst.ScanPageSize, src.ScanPageSize)
+	update(&changed, "scan-buffer", &dst.ScanBuffer, src.ScanBuffer)
+	update(&changed, "s3-object-size", &dst.S3ObjectSize, src.S3ObjectSize)
+	update(&changed, "s3-part-size", &dst.S3PartSize, src.S3PartSize)
+	update(&changed, "sqs-messages", &dst.SQSMessages, src.SQSMessages)
+	update(&changed, "sqs-batch-size", &dst.SQSBatchSize, src.SQSBatchSize)
+	update(&changed, "sqs-wait", &dst.SQSWait, src.SQSWait)
+	update(&changed, "sqs-visibility-timeout", &dst.SQSVisibilityTimeout, src.SQSVisibilityTimeout)
+	update(&changed, "sqs-process-time", &dst.SQSProcessTime, src.SQSProcessTime)
+	update(&changed, "sns-messages", &dst.SNSMessages, src.SNSMessages)
+	update(&changed, "kinesis-shards", &dst.KinesisShards, src.KinesisShards)
+	update(&changed, "kinesis-records", &dst.KinesisRecords, src.KinesisRecords)
+	update(&changed, "kinesis-partition-keys", &dst.KinesisPartitionKeys, src.KinesisPartitionKeys)
+	update(&changed, "lambda-invocations", &dst.LambdaInvocations, src.LambdaInvocations)
+	differs(&ignored, "table-per-iteration", dst.TablePerIteration, src.TablePerIteration)
+	differs(&ignored, "delete-tables", dst.DeleteTables, src.DeleteTables)
+	return changed, ignored
+}
+
+func update[T comparable](changed *[]string, name string, dst *T, src T) {
+	if *dst != src {
+		*dst = src
+		*changed = append(*changed, name)
+	}
+}
+
+func differs[T comparable](names *[]string, name string, dst, src T) {
+	if dst != src {
+		*names = append(*names, name)
+	}
+}
@@ -0,0 +1,114 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyWorkloadSettings(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		args             []string
+		changed, ignored []string
+	}{
+		{name: "unchanged"},
+		{name: "hot", args: []string{"-items", "20", "-sqs-messages", "7"}, changed: []string{"items", "sqs-messages"}},
+		{name: "start only", args: []string{"-table-per-iteration", "-delete-tables"}, ignored: []string{"table-per-iteration", "delete-tables"}},
+		{name: "both", args: []string{"-interval", "5s", "-delete-tables"}, changed: []string{"interval"}, ignored: []string{"delete-tables"}},
+		{name: "not hot", args: []string{"-region", "eu-west-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			running, err := ParseFlags("harness", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, err := ParseFlags("harness", tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed, ignored := applyWorkloadSettings(running, next)
+			if !slices.Equal(changed, tc.changed) || !slices.Equal(ignored, tc.ignored) {
+				t.Errorf("changed %q and ignored %q, want %q and %q", changed, ignored, tc.changed, tc.ignored)
+			}
+			if running.TablePerIteration || running.DeleteTables {
+				t.Error("a table lifecycle flag was applied mid-run")
+			}
+			if changed, _ := applyWorkloadSettings(running, next); len(changed) > 0 {
+				t.Errorf("applying again changed %q", changed)
+			}
+		})
+	}
+}
+
+func TestSSMConfigParse(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		values   map[string]string
+		args     []string
+		items    int
+		messages int
+		err      bool
+	}{
+		{name: "defaults", items: 1, messages: 20},
+		{name: "parameters", values: map[string]string{"items": "20", "sqs-messages": "7"}, items: 20, messages: 7},
+		{name: "command line wins", values: map[string]string{"items": "20"}, args: []string{"-items", "30"}, items: 30, messages: 20},
+		{name: "bad value", values: map[string]string{"items": "many"}, err: true},
+		{name: "unknown flag", values: map[string]string{"no-such-flag": "1"}, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &SSMConfigSource{name: "harness", path: "/harness", args: tc.args}
+			cfg, err := s.parse(tc.values)
+			if tc.err {
+				if err == nil || !strings.Contains(err.Error(), "parameters under /harness") {
+					t.Errorf("parse error %v, want one naming the path", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Items != tc.items || cfg.SQSMessages != tc.messages {
+				t.Errorf("items %d and sqs messages %d, want %d and %d", cfg.Items, cfg.SQSMessages, tc.items, tc.messages)
+			}
+		})
+	}
+}
+
+func TestSSMConfigFetch(t *testing.T) {
+	pages := []string{
+		`{"Parameters":[{"Name":"/harness/items","Value":"20"}],"NextToken":"2"}`,
+		`{"Parameters":[{"Name":"/harness/sqs/messages","Value":"7"}]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Path, NextToken string }
+		json.NewDecoder(r.Body).Decode(&in)
+		if in.Path != "/harness" {
+			t.Errorf("read path %q, want /harness", in.Path)
+		}
+		page := pages[0]
+		if in.NextToken == "2" {
+			page = pages[1]
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	h, _ := newTestHarness(t, srv.URL, "-ssm-path", "/harness", "-quiet")
+	s, cfg, err := LoadSSMConfig(context.Background(), h.AWS, "harness", []string{"-items", "30"}, h.Flags, h.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(s.values, map[string]string{"items": "20", "sqs-messages": "7"}) {
+		t.Errorf("values %v, want both pages keyed by flag name", s.values)
+	}
+	if cfg.Items != 30 || cfg.SQSMessages != 7 {
+		t.Errorf("items %d and sqs messages %d, want the command line's 30 and the parameter's 7", cfg.Items, cfg.SQSMessages)
+	}
+}
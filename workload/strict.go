@@ -0,0 +1,169 @@
+package workload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DivergenceError is the cause of a mismatch under -strict: a response
+// that differs from what the request it answers should have produced.
+type DivergenceError struct {
+	// What names what diverged, such as an item or a table description.
+	What  string
+	Diffs []string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("strict: %s diverges: %s", e.What, strings.Join(e.Diffs, "; "))
+}
+
+// checkItem checks a read item numbered n as the dynamodb module
+// seeded it and, under -strict, that it holds exactly the attributes
+// written.
+func (w *dynamoWorkload) checkItem(op string, n int, item map[string]types.AttributeValue) error {
+	id := itemKey(n)
+	if err := checkSeededItem(op, id, item, w.Flags.Integrity); err != nil {
+		return err
+	}
+	if !w.Flags.Strict {
+		return nil
+	}
+	if diffs := itemDiffs(w.seededItem(n), item); len(diffs) > 0 {
+		return &obsv.HarnessError{Class: obsv.ClassMismatch, Op: op, Err: &DivergenceError{What: "item " + id, Diffs: diffs}}
+	}
+	return nil
+}
+
+// itemDiffs describes how got differs from want, attribute by
+// attribute.
+func itemDiffs(want, got map[string]types.AttributeValue) []string {
+	var diffs []string
+	for _, name := range sortedNames(want, got) {
+		w, g := want[name], got[name]
+		switch {
+		case g == nil:
+			diffs = append(diffs, fmt.Sprintf("attribute %s is missing", name))
+		case w == nil:
+			diffs = append(diffs, fmt.Sprintf("attribute %s was never written", name))
+		case attributeType(w) != attributeType(g):
+			diffs = append(diffs, fmt.Sprintf("attribute %s has type %s, want %s", name, attributeType(g), attributeType(w)))
+		case !scalarEqual(w, g):
+			diffs = append(diffs, fmt.Sprintf("attribute %s has another value than was written", name))
+		}
+	}
+	return diffs
+}
+
+// sortedNames returns the attribute names of all the items, sorted.
+func sortedNames(items ...map[string]types.AttributeValue) []string {
+	var names []string
+	for _, item := range items {
+		for name := range item {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// scalarEqual compares two attributes of one type. Only string, number
+// and binary values are compared, which are all a seeded item holds.
+func scalarEqual(a, b types.AttributeValue) bool {
+	switch a := a.(type) {
+	case *types.AttributeValueMemberS:
+		return a.Value == b.(*types.AttributeValueMemberS).Value
+	case *types.AttributeValueMemberN:
+		return a.Value == b.(*types.AttributeValueMemberN).Value
+	case *types.AttributeValueMemberB:
+		return bytes.Equal(a.Value, b.(*types.AttributeValueMemberB).Value)
+	}
+	return true
+}
+
+// checkTable, under -strict, checks that both the CreateTable response
+// and a fresh DescribeTable of the table match the request.
+func (w *dynamoWorkload) checkTable(ctx context.Context, in *dynamodb.CreateTableInput, created *types.TableDescription) error {
+	if !w.Flags.Strict {
+		return nil
+	}
+	if err := tableDivergence("CreateTable", in, created); err != nil {
+		return err
+	}
+	var out *dynamodb.DescribeTableOutput
+	err := obsv.Timed(ctx, w.Stats, "DescribeTable", w.Flags.ControlTimeout, func(ctx context.Context) error {
+		var err error
+		out, err = w.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: in.TableName})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tableDivergence("DescribeTable", in, out.Table)
+}
+
+// tableDivergence returns a mismatch for op unless desc describes a
+// table as in created it.
+func tableDivergence(op string, in *dynamodb.CreateTableInput, desc *types.TableDescription) error {
+	name := aws.ToString(in.TableName)
+	if desc == nil {
+		return &obsv.HarnessError{Class: obsv.ClassMismatch, Op: op, Err: &DivergenceError{What: "table " + name, Diffs: []string{"no table description returned"}}}
+	}
+	var diffs []string
+	if got := aws.ToString(desc.TableName); got != name {
+		diffs = append(diffs, fmt.Sprintf("TableName is %q", got))
+	}
+	if desc.TableStatus != types.TableStatusCreating && desc.TableStatus != types.TableStatusActive {
+		diffs = append(diffs, fmt.Sprintf("TableStatus is %q, want CREATING or ACTIVE", desc.TableStatus))
+	}
+	keys := func(ks []types.KeySchemaElement) []string {
+		var out []string
+		for _, k := range ks {
+			out = append(out, aws.ToString(k.AttributeName)+" "+string(k.KeyType))
+		}
+		slices.Sort(out)
+		return out
+	}
+	if got, want := keys(desc.KeySchema), keys(in.KeySchema); !slices.Equal(got, want) {
+		diffs = append(diffs, fmt.Sprintf("KeySchema is %v, want %v", got, want))
+	}
+	attrs := func(ds []types.AttributeDefinition) []string {
+		var out []string
+		for _, d := range ds {
+			out = append(out, aws.ToString(d.AttributeName)+" "+string(d.AttributeType))
+		}
+		slices.Sort(out)
+		return out
+	}
+	if got, want := attrs(desc.AttributeDefinitions), attrs(in.AttributeDefinitions); !slices.Equal(got, want) {
+		diffs = append(diffs, fmt.Sprintf("AttributeDefinitions are %v, want %v", got, want))
+	}
+	if in.BillingMode != "" {
+		var got types.BillingMode
+		if desc.BillingModeSummary != nil {
+			got = desc.BillingModeSummary.BillingMode
+		}
+		if got != in.BillingMode {
+			diffs = append(diffs, fmt.Sprintf("BillingMode is %q, want %q", got, in.BillingMode))
+		}
+	}
+	wantStream := in.StreamSpecification != nil && aws.ToBool(in.StreamSpecification.StreamEnabled)
+	gotStream := desc.StreamSpecification != nil && aws.ToBool(desc.StreamSpecification.StreamEnabled)
+	switch {
+	case wantStream != gotStream:
+		diffs = append(diffs, fmt.Sprintf("stream enabled is %v, want %v", gotStream, wantStream))
+	case wantStream && desc.StreamSpecification.StreamViewType != in.StreamSpecification.StreamViewType:
+		diffs = append(diffs, fmt.Sprintf("StreamViewType is %q, want %q", desc.StreamSpecification.StreamViewType, in.StreamSpecification.StreamViewType))
+	}
+	if len(diffs) > 0 {
+		return &obsv.HarnessError{Class: obsv.ClassMismatch, Op: op, Err: &DivergenceError{What: "table " + name, Diffs: diffs}}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestStrictConforms(t *testing.T) {
+	for _, mode := range verifyModes {
+		t.Run(mode, func(t *testing.T) {
+			h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "30",
+				"-strict", "-integrity", "-verify-mode", mode, "-quiet")
+			w := &dynamoWorkload{Harness: h, client: h.DynamoClient()}
+			if err := w.RunIteration(context.Background()); err != nil {
+				t.Fatalf("iteration: %v", err)
+			}
+			if n := h.Stats.TakeWindow().Ops["DescribeTable"].Count; n != 1 {
+				t.Errorf("%d DescribeTable calls, want the one checking the new table", n)
+			}
+		})
+	}
+}
+
+// divergentBackend answers reads with an attribute never written and
+// describes tables without their billing mode.
+type divergentBackend struct {
+	*ddbtest.Memory
+}
+
+func (d *divergentBackend) GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	out, err := d.Memory.GetItem(ctx, in, optFns...)
+	if err == nil && out.Item != nil {
+		out.Item = maps.Clone(out.Item)
+		out.Item["LastModified"] = &types.AttributeValueMemberN{Value: "1"}
+	}
+	return out, err
+}
+
+func (d *divergentBackend) DescribeTable(ctx context.Context, in *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	out, err := d.Memory.DescribeTable(ctx, in, optFns...)
+	if err == nil {
+		out.Table.BillingModeSummary = nil
+	}
+	return out, err
+}
+
+func TestStrictDivergence(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		op     string
+		detail string
+	}{
+		{name: "table", args: []string{"-strict"}, op: "DescribeTable", detail: "BillingMode"},
+		{name: "item", args: []string{"-strict"}, op: "GetItem", detail: "attribute LastModified was never written"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, _ := newTestHarness(t, ddbtest.Offline, append([]string{"-backend", "memory", "-items", "3", "-quiet"}, tc.args...)...)
+			w := &dynamoWorkload{Harness: h, client: &divergentBackend{Memory: h.Memory}}
+			if tc.op == "GetItem" {
+				// Create the table through the conforming backend; the
+				// next iteration reuses it without describing it again.
+				w.client = h.DynamoClient()
+				if err := w.RunIteration(context.Background()); err != nil {
+					t.Fatalf("first iteration: %v", err)
+				}
+				w.client = &divergentBackend{Memory: h.Memory}
+			}
+
+			err := w.RunIteration(context.Background())
+			var he *obsv.HarnessError
+			var de *DivergenceError
+			if !errors.As(err, &he) || he.Op != tc.op || he.Class != obsv.ClassMismatch || !errors.As(err, &de) {
+				t.Fatalf("got %v, want a %s divergence", err, tc.op)
+			}
+			if !strings.Contains(err.Error(), tc.detail) {
+				t.Errorf("%v does not mention %q", err, tc.detail)
+			}
+		})
+	}
+}
+
+func TestWithoutStrictDivergenceIsIgnored(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "3", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: &divergentBackend{Memory: h.Memory}}
+	if err := w.RunIteration(context.Background()); err != nil {
+		t.Errorf("iteration without -strict: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package workload
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// TestConfigLoader loads the SDK config a test harness calls with, and
+// the failover its requests go through.
+type TestConfigLoader func(flags *Config, logger *obsv.Logger, stats *obsv.Stats) (aws.Config, *EndpointFailover, error)
+
+// NewTestHarness builds a harness for endpoint from args for t, loading
+// its config with load or, when load is nil, failing over between
+// endpoints the way the command does. The returned sink records every
+// event the harness logs. It lives here rather than in ddbtest, which
+// this package imports, so that the tests of this package and of the
+// command share it.
+func NewTestHarness(t testing.TB, endpoint string, load TestConfigLoader, args ...string) (*Harness, *ddbtest.RecordingSink) {
+	t.Helper()
+	flags, err := ParseFlags("harness", append([]string{"-endpoint", endpoint}, args...))
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	sink := &ddbtest.RecordingSink{}
+	logger := &obsv.Logger{Verbosity: flags.Verbosity}
+	logger.AddSink(sink)
+
+	if load == nil {
+		load = loadTestConfig
+	}
+	stats := obsv.NewStats()
+	cfg, failover, err := load(flags, logger, stats)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	h := &Harness{
+		AWS:        cfg,
+		Flags:      flags,
+		Logger:     logger,
+		Stats:      stats,
+		Checkpoint: NewCheckpoint(""),
+		Endpoints:  failover,
+	}
+	if flags.Backend == BackendMemory {
+		h.Memory = ddbtest.NewMemory()
+	}
+	return h, sink
+}
+
+func loadTestConfig(flags *Config, logger *obsv.Logger, _ *obsv.Stats) (aws.Config, *EndpointFailover, error) {
+	failover, err := NewEndpointFailover(flags, logger)
+	if err != nil {
+		return aws.Config{}, nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(flags.Region),
+		config.WithBaseEndpoint(flags.Endpoint),
+		config.WithCredentialsProvider(ddbtest.TestCredentials),
+		config.WithAPIOptions([]func(*middleware.Stack) error{failover.Middleware}),
+	)
+	return cfg, failover, err
+}
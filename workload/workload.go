@@ -0,0 +1,286 @@
+// Package workload is the harness's engine: its configuration, the
+// service modules run on each iteration, and the loop that runs them
+// with checkpoints, readiness probes and endpoint failover.
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Workload is one service module exercised on every loop iteration.
+type Workload interface {
+	Name() string
+	RunIteration(ctx context.Context) error
+}
+
+// finisher is implemented by workloads that hold resources across
+// iterations, which RunLoop releases once the loop ends.
+type finisher interface {
+	Finish(ctx context.Context) error
+}
+
+// ModuleNames lists the modules accepted by -modules.
+var ModuleNames = []string{"dynamodb", "s3", "sqs", "sns", "kinesis", "kms", "lambda"}
+
+// ModuleServices lists the LocalStack services each module calls.
+var ModuleServices = map[string][]string{
+	"dynamodb": {"dynamodb"},
+	"s3":       {"s3"},
+	"sqs":      {"sqs"},
+	"sns":      {"sns", "sqs"},
+	"kinesis":  {"kinesis"},
+	"kms":      {"kms", "dynamodb"},
+	"lambda":   {"lambda"},
+}
+
+// Backends accepted by -backend.
+const (
+	BackendAWS    = "aws"
+	BackendMemory = "memory"
+)
+
+// Harness bundles what every module shares: the SDK config with the
+// credential logging provider, the run flags, output and metrics. AWS,
+// Flags, Logger, Stats and Checkpoint are required; the rest are set
+// only when the run uses them.
+type Harness struct {
+	AWS          aws.Config
+	Flags        *Config
+	Logger       *obsv.Logger
+	Stats        *obsv.Stats
+	Checkpoint   *Checkpoint
+	Lifecycle    *LifecyclePublisher
+	ConfigSource *SSMConfigSource
+	// Memory, if set, serves every DynamoDB call in place of AWS.
+	Memory    *ddbtest.Memory
+	Features  *Features
+	Endpoints *EndpointFailover
+	// Drain, if set, stops the loop for shutdown.
+	Drain *obsv.Drain
+}
+
+// DynamoClient returns the DynamoDB client for -backend. Every memory
+// client shares the harness's one store.
+func (h *Harness) DynamoClient() ddbtest.API {
+	if h.Memory != nil {
+		return h.Memory
+	}
+	return dynamodb.NewFromConfig(h.AWS)
+}
+
+// endpoint returns the endpoint requests currently go to, which moves
+// along -endpoint as the run fails over.
+func (h *Harness) endpoint() string {
+	if h.Endpoints == nil {
+		return h.Flags.Endpoint
+	}
+	return h.Endpoints.Endpoint().String()
+}
+
+// NewWorkloads builds the modules selected with -modules, in order.
+func NewWorkloads(h *Harness) ([]Workload, error) {
+	var workloads []Workload
+	for _, name := range h.Flags.Modules {
+		switch name {
+		case "dynamodb":
+			client := h.DynamoClient()
+			if h.Flags.ReadCacheTTL > 0 {
+				client = ddbtest.NewCache(client, h.Flags.ReadCacheTTL, h.Stats)
+			}
+			workloads = append(workloads, &dynamoWorkload{
+				Harness: h,
+				client:  client,
+			})
+		case "s3":
+			workloads = append(workloads, &s3Workload{
+				Harness: h,
+				client: s3.NewFromConfig(h.AWS, func(o *s3.Options) {
+					// LocalStack serves buckets by path rather than virtual host.
+					o.UsePathStyle = true
+				}),
+			})
+		case "sqs":
+			workloads = append(workloads, &sqsWorkload{
+				Harness: h,
+				client:  sqs.NewFromConfig(h.AWS),
+			})
+		case "sns":
+			workloads = append(workloads, &snsWorkload{
+				Harness: h,
+				client:  sns.NewFromConfig(h.AWS),
+				queues: &sqsWorkload{
+					Harness: h,
+					client:  sqs.NewFromConfig(h.AWS),
+				},
+			})
+		case "kinesis":
+			workloads = append(workloads, &kinesisWorkload{
+				Harness: h,
+				client:  kinesis.NewFromConfig(h.AWS),
+			})
+		case "kms":
+			workloads = append(workloads, &kmsWorkload{
+				Harness: h,
+				client:  kms.NewFromConfig(h.AWS),
+				tables: &dynamoWorkload{
+					Harness: h,
+					client:  h.DynamoClient(),
+				},
+			})
+		case "lambda":
+			if h.Flags.LambdaFunction == "" {
+				return nil, fmt.Errorf("module lambda requires -lambda-function")
+			}
+			workloads = append(workloads, &lambdaWorkload{
+				Harness: h,
+				client:  lambda.NewFromConfig(h.AWS),
+			})
+		default:
+			return nil, fmt.Errorf("unknown module %q (want one of %s)", name, strings.Join(ModuleNames, ", "))
+		}
+	}
+	return workloads, nil
+}
+
+// RunLoop runs every workload once per iteration until the iteration
+// or duration bound is reached, pausing between iterations, and then
+// lets the workloads release what they kept between iterations.
+//
+// A workload that fails is logged and counted, and the loop moves on to
+// the next workload; the dynamodb module picks up from its checkpoint on
+// the next iteration. RunLoop then fails with the last such error once
+// the loop ends. With -fail-fast, or when ctx is done or the failure is
+// one of configuration, it stops at the first failure instead.
+//
+// Once h.Drain starts, no module starts another iteration; the one
+// running stops at its next call, but its calls in flight may finish
+// until the drain times out. The workloads are then released all the
+// same, and the interrupted iteration is neither counted nor
+// checkpointed as done.
+func RunLoop(ctx context.Context, h *Harness, workloads []Workload) error {
+	var failed int
+	var last error
+	var deadline time.Time
+	if h.Flags.Duration > 0 {
+		deadline = time.Now().Add(h.Flags.Duration)
+	}
+	loopCtx, cancel := h.Drain.Bind(ctx)
+	defer cancel()
+
+loop:
+	for i := h.Checkpoint.Iteration + 1; h.Flags.Iterations == 0 || i <= h.Flags.Iterations; i++ {
+		if next := h.ConfigSource.Take(); next != nil {
+			changed, ignored := applyWorkloadSettings(h.Flags, next)
+			if len(changed) > 0 {
+				h.Logger.Operationf("Applied changed settings from %s: %s", h.Flags.SSMPath, strings.Join(changed, ", "))
+			}
+			if len(ignored) > 0 {
+				h.Logger.Operationf("Ignoring changed settings from %s until the next start: %s", h.Flags.SSMPath, strings.Join(ignored, ", "))
+			}
+		}
+		var iterErr *obsv.HarnessError
+		for _, w := range workloads {
+			if h.Drain.Draining() {
+				h.Logger.Operationf("Shutting down before iteration %d of module %s", i, w.Name())
+				break loop
+			}
+			err := runBudgeted(loopCtx, h.Flags.IterationBudget, w)
+			if err == nil {
+				continue
+			}
+			if h.Drain.Draining() {
+				h.Logger.Operationf("Iteration %d: module %s stopped for shutdown: %v", i, w.Name(), err)
+				break loop
+			}
+			he := obsv.Classify(w.Name(), err).(*obsv.HarnessError)
+			if h.Flags.FailFast || ctx.Err() != nil || he.Class == obsv.ClassConfig {
+				return err
+			}
+			h.Logger.Operationf("Iteration %d: module %s failed (%s): %v", i, w.Name(), he.Class, err)
+			iterErr = he
+		}
+		h.Checkpoint.Iteration = i
+		if err := h.Checkpoint.save(); err != nil {
+			return &obsv.HarnessError{Class: obsv.ClassUnknown, Op: "SaveCheckpoint", Err: err}
+		}
+		if iterErr != nil {
+			failed++
+			last = iterErr
+			h.Stats.IterationFailed(iterErr.Class)
+		} else {
+			h.Stats.IterationDone()
+		}
+
+		if i == h.Flags.Iterations {
+			break
+		}
+
+		// Keep app alive a bit to see TTL logs
+		pause := h.Flags.Interval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				break
+			}
+			if remaining < pause {
+				pause = remaining
+			}
+		}
+		select {
+		case <-time.After(pause):
+		case <-h.Drain.Stopping():
+		}
+
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			break
+		}
+	}
+
+	for _, w := range workloads {
+		if f, ok := w.(finisher); ok {
+			if err := f.Finish(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if last != nil {
+		return obsv.Classify("RunLoop", fmt.Errorf("%d iterations failed, last: %w", failed, last))
+	}
+	return nil
+}
+
+// runBudgeted runs one iteration of w within budget. An iteration that
+// fails once its budget is spent, however the running call reported it,
+// fails with the budget's BudgetError naming the phase that ran out.
+func runBudgeted(ctx context.Context, budget time.Duration, w Workload) error {
+	iterCtx, cancel := obsv.WithBudget(ctx, budget)
+	defer cancel()
+	err := w.RunIteration(iterCtx)
+	var budgetErr *obsv.BudgetError
+	if err != nil && errors.As(context.Cause(iterCtx), &budgetErr) && !errors.As(err, &budgetErr) {
+		err = &obsv.HarnessError{Class: obsv.ClassUnavailable, Op: w.Name(), Err: fmt.Errorf("%w: %w", budgetErr, err)}
+	}
+	return err
+}
+
+// inPhase runs fn as the named phase of the iteration's budget.
+func inPhase(ctx context.Context, name string, fn func(context.Context) error) error {
+	ctx, done := obsv.Phase(ctx, name)
+	defer done()
+	return fn(ctx)
+}
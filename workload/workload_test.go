@@ -0,0 +1,163 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Lou-Varndell/files/ddbtest"
+	"github.com/Lou-Varndell/files/obsv"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go"
+)
+
+// flakyWorkload fails the iterations listed in fail with a throttling error.
+type flakyWorkload struct {
+	runs int
+	fail map[int]bool
+}
+
+func (w *flakyWorkload) Name() string { return "flaky" }
+
+func (w *flakyWorkload) RunIteration(ctx context.Context) error {
+	w.runs++
+	if w.fail[w.runs] {
+		return obsv.Classify("PutItem", &smithy.GenericAPIError{Code: "ThrottlingException"})
+	}
+	return nil
+}
+
+func TestRunLoopCarriesOnAfterFailure(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-iterations", "3", "-interval", "0", "-quiet")
+	flaky := &flakyWorkload{fail: map[int]bool{2: true}}
+	after := &flakyWorkload{}
+
+	err := RunLoop(context.Background(), h, []Workload{flaky, after})
+	if obsv.ClassOf(err) != obsv.ClassThrottling {
+		t.Fatalf("run loop: %v, want the throttling failure reported at the end", err)
+	}
+	if flaky.runs != 3 || after.runs != 3 || h.Checkpoint.Iteration != 3 {
+		t.Errorf("ran %d and %d times to iteration %d, want every module run in all 3 iterations",
+			flaky.runs, after.runs, h.Checkpoint.Iteration)
+	}
+	var report strings.Builder
+	h.Stats.Report(&report)
+	if !strings.Contains(report.String(), "Iterations: 2,") || !strings.Contains(report.String(), "Failed iterations: 1 (throttling 1)") {
+		t.Errorf("report does not count 2 iterations done and 1 failed:\n%s", report.String())
+	}
+}
+
+func TestRunLoopFailFast(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-iterations", "3", "-interval", "0", "-fail-fast", "-quiet")
+	flaky := &flakyWorkload{fail: map[int]bool{2: true}}
+	after := &flakyWorkload{}
+
+	if err := RunLoop(context.Background(), h, []Workload{flaky, after}); err == nil {
+		t.Fatal("run loop succeeded, want the iteration 2 failure")
+	}
+	if flaky.runs != 2 || after.runs != 1 {
+		t.Errorf("ran %d and %d times, want the run stopped at the second iteration's failure", flaky.runs, after.runs)
+	}
+}
+
+// hangingWrites never completes a PutItem before its context ends.
+type hangingWrites struct {
+	*ddbtest.Memory
+}
+
+func (h *hangingWrites) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestIterationBudgetNamesPhase(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "3",
+		"-iteration-budget", "100ms", "-data-timeout", "0", "-quiet")
+	w := &dynamoWorkload{Harness: h, client: &hangingWrites{Memory: h.Memory}}
+	start := time.Now()
+	err := runBudgeted(context.Background(), h.Flags.IterationBudget, w)
+
+	var budgetErr *obsv.BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Phase() != "write" {
+		t.Fatalf("got %v, want the budget to run out in the write phase", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("iteration took %s, want it cut short near its 100ms budget", elapsed)
+	}
+	if obsv.ClassOf(err) != obsv.ClassUnavailable {
+		t.Errorf("class %s, want %s", obsv.ClassOf(err), obsv.ClassUnavailable)
+	}
+}
+
+// gatedWrites holds the first PutItem until release is closed.
+type gatedWrites struct {
+	*ddbtest.Memory
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	puts    atomic.Int32
+}
+
+func (g *gatedWrites) PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	g.once.Do(func() {
+		close(g.started)
+		<-g.release
+	})
+	g.puts.Add(1)
+	return g.Memory.PutItem(ctx, in, optFns...)
+}
+
+func TestRunLoopDrains(t *testing.T) {
+	h, _ := newTestHarness(t, ddbtest.Offline, "-backend", "memory", "-items", "5",
+		"-iterations", "3", "-interval", "0", "-delete-tables", "-quiet")
+	h.Drain = obsv.NewDrain()
+	client := &gatedWrites{Memory: h.Memory, started: make(chan struct{}), release: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		done <- RunLoop(context.Background(), h, []Workload{&dynamoWorkload{Harness: h, client: client}})
+	}()
+
+	<-client.started
+	h.Drain.Start(time.Minute)
+	close(client.release)
+	if err := <-done; err != nil {
+		t.Fatalf("run loop: %v", err)
+	}
+	cp := h.Checkpoint
+	if n := client.puts.Load(); n != 1 || cp.Written != 1 {
+		t.Errorf("%d items put and %d checkpointed, want only the one in flight", n, cp.Written)
+	}
+	if cp.Iteration != 0 {
+		t.Errorf("checkpoint counts %d iterations, want the interrupted one left undone", cp.Iteration)
+	}
+	if n := h.Stats.TakeWindow().Ops["DeleteTable"].Count; n != 1 {
+		t.Errorf("%d DeleteTable calls, want the run's table cleaned up", n)
+	}
+}
+
+func TestRunLoopBounds(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		min, max int
+	}{
+		{"iterations", []string{"-iterations", "4", "-interval", "0"}, 4, 4},
+		{"duration", []string{"-iterations", "0", "-duration", "150ms", "-interval", "50ms"}, 2, 5},
+		{"first bound wins", []string{"-iterations", "2", "-duration", "1h", "-interval", "0"}, 2, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, _ := newTestHarness(t, ddbtest.Offline, append([]string{"-backend", "memory", "-quiet"}, tc.args...)...)
+			w := &flakyWorkload{}
+			if err := RunLoop(context.Background(), h, []Workload{w}); err != nil {
+				t.Fatalf("run loop: %v", err)
+			}
+			if w.runs < tc.min || w.runs > tc.max {
+				t.Errorf("ran %d iterations, want %d to %d", w.runs, tc.min, tc.max)
+			}
+		})
+	}
+}